@@ -0,0 +1,78 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EAN-5 校验值决定 5 位数字的奇偶排列（0 为 L，1 为 G）
+var ean5Parity = [10]string{"11000", "10100", "10010", "10001", "01100", "00110", "00011", "01010", "01001", "00101"}
+
+// EAN-2 数值除以 4 的余数决定 2 位数字的奇偶排列
+var ean2Parity = [4]string{"00", "01", "10", "11"}
+
+// 主码与附加码之间的空白模块数（标准为 7-12）
+const addonGap = 9
+
+// CheckAddonType 检查条码类型是否可以添加附加码
+func CheckAddonType(barcodeType string) error {
+	switch barcodeType = strings.ToUpper(barcodeType); barcodeType {
+	case "EAN13", "UPCA", "UPCE", "ISBN", "ISSN":
+		return nil
+	}
+	return fmt.Errorf("%s条形码不支持附加码，只有EAN13、UPCA、UPCE、ISBN、ISSN可以添加", barcodeType)
+}
+
+// AddAddon 在 EAN-13/UPC 条码右侧追加 2 位或 5 位附加码
+// 图书（978/979 开头）必须使用 5 位价格码
+func (c *Code) AddAddon(addon string) error {
+	if err := CheckAddonType(c.Type); err != nil {
+		return err
+	}
+	if (len(addon) != 2 && len(addon) != 5) || !allDigits(addon) {
+		return fmt.Errorf("附加码必须是2位或5位数字")
+	}
+	if (strings.HasPrefix(c.Text, "978") || strings.HasPrefix(c.Text, "979")) && len(c.Text) == 13 && len(addon) != 5 {
+		return fmt.Errorf("图书（ISBN，978/979开头）的附加码必须是5位价格码")
+	}
+
+	for i := 0; i < addonGap; i++ {
+		c.Modules = append(c.Modules, false)
+	}
+	c.Modules = append(c.Modules, encodeAddon(addon)...)
+	c.Text += " " + addon
+	return nil
+}
+
+// 编码附加码：起始符 1011，数字之间以 01 分隔
+func encodeAddon(addon string) []bool {
+	var parity string
+	if len(addon) == 2 {
+		parity = ean2Parity[(int(addon[0]-'0')*10+int(addon[1]-'0'))%4]
+	} else {
+		sum := 0
+		for i := 0; i < 5; i++ {
+			d := int(addon[i] - '0')
+			if i%2 == 0 {
+				sum += d * 3
+			} else {
+				sum += d * 9
+			}
+		}
+		parity = ean5Parity[sum%10]
+	}
+
+	modules := appendBits(nil, "1011")
+	for i := 0; i < len(addon); i++ {
+		if i > 0 {
+			modules = appendBits(modules, "01")
+		}
+		d := addon[i] - '0'
+		if parity[i] == '1' {
+			modules = appendBits(modules, eanG[d])
+		} else {
+			modules = appendBits(modules, eanL[d])
+		}
+	}
+	return modules
+}
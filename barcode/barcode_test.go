@@ -0,0 +1,247 @@
+package barcode
+
+import (
+	"strings"
+	"testing"
+)
+
+// 将模块序列转换为条空宽度序列（每个宽度一位数字）
+func moduleWidths(modules []bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(modules); {
+		j := i
+		for j < len(modules) && modules[j] == modules[i] {
+			j++
+		}
+		sb.WriteByte(byte('0' + j - i))
+		i = j
+	}
+	return sb.String()
+}
+
+func TestEANCheckDigit(t *testing.T) {
+	cases := []struct {
+		digits string
+		want   int
+	}{
+		{"590123412345", 7},      // EAN-13
+		{"400638133393", 1},      // EAN-13
+		{"9638507", 4},           // EAN-8
+		{"03600029145", 2},       // UPC-A
+		{"1540014128876", 3},     // ITF-14
+		{"10614141234567890", 8}, // SSCC
+	}
+	for _, tc := range cases {
+		if got := EANCheckDigit(tc.digits); got != tc.want {
+			t.Errorf("%s 的校验码为 %d，应为 %d", tc.digits, got, tc.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name  string
+		fn    func(string) (string, error)
+		input string
+		want  string
+	}{
+		{"ISBN", NormalizeISBN, "0-306-40615-2", "9780306406157"},
+		{"ISBN", NormalizeISBN, "0-8044-2957-X", "9780804429573"},
+		{"ISBN", NormalizeISBN, "978-0-306-40615", "9780306406157"},
+		{"ISSN", NormalizeISSN, "0317-8471", "9770317847001"},
+		{"ITF-14", NormalizeITF14, "1540014128876", "15400141288763"},
+		{"UPC-A", NormalizeUPCA, "03600029145", "036000291452"},
+	}
+	for _, tc := range cases {
+		got, err := tc.fn(tc.input)
+		if err != nil || got != tc.want {
+			t.Errorf("%s %s 规范为 %q, %v，应为 %s", tc.name, tc.input, got, err, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"0-306-40615-3", "9790306406157"} {
+		if _, err := NormalizeISBN(bad); err == nil {
+			t.Errorf("ISBN %s 校验码错误应返回错误", bad)
+		}
+	}
+}
+
+// UPC-E 的四种零压缩规则（末位 0-2、3、4、5-9）
+func TestUPCE(t *testing.T) {
+	cases := []struct {
+		upce, upca string
+	}{
+		{"01234505", "012000003455"},
+		{"01234531", "012300000451"},
+		{"01234543", "012340000053"},
+		{"01234565", "012345000065"},
+	}
+	for _, tc := range cases {
+		if got, err := ExpandUPCE(tc.upce); err != nil || got != tc.upca {
+			t.Errorf("UPC-E %s 展开为 %q, %v，应为 %s", tc.upce, got, err, tc.upca)
+		}
+		if got, err := CompressUPCA(tc.upca); err != nil || got != tc.upce {
+			t.Errorf("UPC-A %s 压缩为 %q, %v，应为 %s", tc.upca, got, err, tc.upce)
+		}
+		if got, err := ExpandUPCE(tc.upce[:7]); err != nil || got != tc.upca {
+			t.Errorf("UPC-E %s 展开为 %q, %v，应为 %s", tc.upce[:7], got, err, tc.upca)
+		}
+	}
+
+	if _, err := ExpandUPCE("01234564"); err == nil {
+		t.Error("UPC-E 校验码错误应返回错误")
+	}
+	if _, err := CompressUPCA("012345678905"); err == nil {
+		t.Error("不符合零压缩规则的 UPC-A 应返回错误")
+	}
+	// 同一个 UPC-A 只有一种 UPC-E 形式
+	if _, err := NormalizeUPCE("01200005"); err == nil {
+		t.Error("非规范的 UPC-E 应返回错误")
+	}
+}
+
+func TestGS1CheckDigit(t *testing.T) {
+	for _, data := range []string{"(01)09501101530003", "(00)106141412345678908", "(01)09501101530003(17)251231(10)AB-12"} {
+		if _, err := ParseGS1(data); err != nil {
+			t.Errorf("%s: %v", data, err)
+		}
+	}
+
+	cases := []struct {
+		data, want string
+	}{
+		{"(01)09501101530004", "第14位应为 3"},
+		{"(00)106141412345678909", "第18位应为 8"},
+		{"(17)251301", "日期无效"},
+		{"(01)0950110153000", "必须是14位"},
+	}
+	for _, tc := range cases {
+		_, err := ParseGS1(tc.data)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s 应返回包含 %q 的错误: %v", tc.data, tc.want, err)
+		}
+	}
+}
+
+// CODE93 的 C、K 校验字符：TEST93 为 + 和 6
+func TestCode93CheckChars(t *testing.T) {
+	modules, err := encodeCode93("TEST93")
+	if err != nil {
+		t.Fatal(err)
+	}
+	widths := moduleWidths(modules)
+	if !strings.HasPrefix(widths, code93Guard) || !strings.HasSuffix(widths, code93Guard+"1") {
+		t.Fatalf("起始符/终止符错误: %s", widths)
+	}
+
+	var decoded []byte
+	for i := len(code93Guard); i+6 <= len(widths)-len(code93Guard)-1; i += 6 {
+		found := false
+		for v, p := range code93Patterns {
+			if widths[i:i+6] == p {
+				decoded = append(decoded, code93Chars[v])
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("无法识别的 CODE93 字符 %s", widths[i:i+6])
+		}
+	}
+	if string(decoded) != "TEST93+6" {
+		t.Errorf("CODE93 符号字符为 %s，应为 TEST93+6", decoded)
+	}
+
+	// 全ASCII的小写字母使用 (+) 移位符
+	if expanded, _ := code93Expand("a1"); expanded != "dA1" {
+		t.Errorf("a1 展开为 %q，应为 dA1", expanded)
+	}
+}
+
+// CODE128 校验符为 (起始符 + Σ 位置×码值) mod 103
+func TestCode128CheckChar(t *testing.T) {
+	cases := []struct {
+		data   string
+		values []int
+		check  int
+	}{
+		{"AB", []int{code128StartB, 33, 34}, 102},
+		{"123456", []int{code128StartC, 12, 34, 56}, 44},
+		{"A\x01", []int{code128StartA, 33, 65}, 60},
+	}
+	for _, tc := range cases {
+		c, err := EncodeCode128(tc.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(c.values) != len(tc.values) {
+			t.Fatalf("%q 的码值为 %v，应为 %v", tc.data, c.values, tc.values)
+		}
+		for i := range tc.values {
+			if c.values[i] != tc.values[i] {
+				t.Fatalf("%q 的码值为 %v，应为 %v", tc.data, c.values, tc.values)
+			}
+		}
+
+		widths := moduleWidths(c.modules())
+		check := widths[len(widths)-13 : len(widths)-7]
+		if check != code128Patterns[tc.check] {
+			t.Errorf("%q 的校验符为 %s，应为码值 %d (%s)", tc.data, check, tc.check, code128Patterns[tc.check])
+		}
+		if len(c.modules()) != c.Width() {
+			t.Errorf("%q 的模块数为 %d，Width 返回 %d", tc.data, len(c.modules()), c.Width())
+		}
+	}
+}
+
+// 打印机 GS k 73 数据与直接编码得到相同的条码
+func TestCode128Payload(t *testing.T) {
+	for _, data := range []string{"AB", "123456", "No.12345678", "A\x01b{c", "1234a5678"} {
+		c, err := EncodeCode128(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, err := ParseCode128Payload(c.Payload())
+		if err != nil {
+			t.Fatalf("%q 的打印机数据 %q 无法解析: %v", data, c.Payload(), err)
+		}
+		if moduleWidths(code.Modules) != moduleWidths(c.modules()) {
+			t.Errorf("%q 的打印机数据 %q 与直接编码不一致", data, c.Payload())
+		}
+	}
+
+	for _, payload := range []string{"AB", "{C\x64", "{D12", "{B{"} {
+		if _, err := ParseCode128Payload([]byte(payload)); err == nil {
+			t.Errorf("无效的打印机数据 %q 应返回错误", payload)
+		}
+	}
+}
+
+// A 子集编码：数字不使用 C 子集，控制字符和 NUL 直接编码，小写字母报错
+func TestEncodeCode128A(t *testing.T) {
+	c, err := EncodeCode128A("A1\x00\x1f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{code128StartA, 33, 17, 64, 95}
+	if len(c.values) != len(want) {
+		t.Fatalf("码值为 %v，应为 %v", c.values, want)
+	}
+	for i := range want {
+		if c.values[i] != want[i] {
+			t.Fatalf("码值为 %v，应为 %v", c.values, want)
+		}
+	}
+	if string(c.Payload()) != "{AA1\x00\x1f" {
+		t.Errorf("打印机数据为 %q", c.Payload())
+	}
+	code, err := ParseCode128Payload(c.Payload())
+	if err != nil || moduleWidths(code.Modules) != moduleWidths(c.modules()) {
+		t.Errorf("打印机数据与直接编码不一致: %v", err)
+	}
+
+	for _, data := range []string{"", "ABc", "A{", "A\x80"} {
+		if _, err := EncodeCode128A(data); err == nil {
+			t.Errorf("%q 不能用 A 子集编码，应返回错误", data)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Codabar 字符集及宽窄序列（条空交替共7个单元，1 为宽）
+const codabarChars = "0123456789-$:/.+ABCD"
+
+var codabarPatterns = [20]string{
+	"0000011", "0000110", "0001001", "1100000", "0010010",
+	"1000010", "0100001", "0100100", "0110000", "1001000",
+	"0001100", "0011000", "1000101", "1010001", "1010100",
+	"0010101", "0011010", "0101001", "0001011", "0001110",
+}
+
+// 默认宽窄比
+const codabarWide = 3
+
+// NormalizeCodabar 校验 Codabar 数据并转为大写
+// 数据必须以 A/B/C/D 作为起始符和终止符，中间只能是数字和 - $ : / . +
+func NormalizeCodabar(data string) (string, error) {
+	data = strings.ToUpper(data)
+	if len(data) < 3 {
+		return "", fmt.Errorf("CODABAR条形码必须包含起始符、数据和终止符，如 A12345B")
+	}
+	if !strings.ContainsRune("ABCD", rune(data[0])) {
+		return "", charError("CODABAR", data, 0, "起始符必须是 A、B、C 或 D")
+	}
+	last := len(data) - 1
+	if !strings.ContainsRune("ABCD", rune(data[last])) {
+		return "", charError("CODABAR", data, last, "终止符必须是 A、B、C 或 D")
+	}
+	for i := 1; i < last; i++ {
+		switch {
+		case strings.ContainsRune("ABCD", rune(data[i])):
+			return "", charError("CODABAR", data, i, "A、B、C、D 只能作为起始符和终止符")
+		case !strings.ContainsRune("0123456789-$:/.+", rune(data[i])):
+			return "", charError("CODABAR", data, i, "只支持数字和 - $ : / . +")
+		}
+	}
+	return data, nil
+}
+
+// EncodeCodabar 编码 Codabar，narrow 和 wide 为窄、宽单元的模块数
+// 宽窄比由 wide/narrow 决定，通常为 2-3
+func EncodeCodabar(data string, narrow, wide int) (*Code, error) {
+	if narrow < 1 || wide <= narrow {
+		return nil, fmt.Errorf("CODABAR宽单元必须大于窄单元: %d/%d", wide, narrow)
+	}
+	text, err := NormalizeCodabar(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []bool
+	for i := 0; i < len(text); i++ {
+		if i > 0 {
+			modules = appendRun(modules, narrow, false) // 字符间隔
+		}
+		pattern := codabarPatterns[strings.IndexByte(codabarChars, text[i])]
+		for j := 0; j < len(pattern); j++ {
+			n := narrow
+			if pattern[j] == '1' {
+				n = wide
+			}
+			modules = appendRun(modules, n, j%2 == 0)
+		}
+	}
+	return &Code{Type: "CODABAR", Text: text, Modules: modules}, nil
+}
+
+// 追加 n 个相同的模块
+func appendRun(modules []bool, n int, bar bool) []bool {
+	for i := 0; i < n; i++ {
+		modules = append(modules, bar)
+	}
+	return modules
+}
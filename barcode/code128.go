@@ -0,0 +1,305 @@
+package barcode
+
+import (
+	"fmt"
+)
+
+// CODE128 符号字符的条空宽度，下标为码值
+var code128Patterns = [107]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+// CODE128 特殊码值
+const (
+	code128Shift  = 98
+	code128CodeC  = 99
+	code128CodeB  = 100
+	code128CodeA  = 101
+	code128FNC1   = 102
+	code128StartA = 103
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// CODE128 子集
+const (
+	code128SetA = iota
+	code128SetB
+	code128SetC
+)
+
+var (
+	code128SetNames = [3]byte{'A', 'B', 'C'}
+	code128Starts   = [3]int{code128StartA, code128StartB, code128StartC}
+	code128Switches = [3]int{code128CodeA, code128CodeB, code128CodeC}
+)
+
+// Code128 CODE128 编码结果，子集切换为最短序列
+type Code128 struct {
+	values  []int  // 码值序列，含起始符，不含校验符和终止符
+	payload []byte // 打印机 GS k 73 的数据部分，以 {A/{B/{C 开头
+}
+
+// EncodeCode128 按最短符号序列选择 A/B/C 子集编码 CODE128
+// 连续数字使用 C 子集，控制字符使用 A 子集，单个字符的临时切换使用 SHIFT
+func EncodeCode128(data string) (*Code128, error) {
+	if data == "" {
+		return nil, fmt.Errorf("CODE128条形码数据不能为空")
+	}
+	chars := make([]int, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] > 127 {
+			return nil, charError("CODE128", data, i, "只支持ASCII字符")
+		}
+		chars[i] = int(data[i])
+	}
+	return planCode128(chars), nil
+}
+
+// EncodeCode128A 只使用 A 子集编码 CODE128：大写字母、数字、标点和控制字符（ASCII 0-95）
+// NUL 等控制字符通过 GS k m=73 的长度字节发送，不会截断数据
+func EncodeCode128A(data string) (*Code128, error) {
+	if data == "" {
+		return nil, fmt.Errorf("CODE128条形码数据不能为空")
+	}
+	c := &Code128{
+		values:  []int{code128StartA},
+		payload: []byte{'{', 'A'},
+	}
+	for i := 0; i < len(data); i++ {
+		v := code128Value(int(data[i]), code128SetA)
+		if v < 0 {
+			return nil, charError("CODE128A", data, i, "A 子集只支持大写字母、数字、标点和控制字符（ASCII 0-95）")
+		}
+		c.values = append(c.values, v)
+		c.payload = append(c.payload, data[i]) // A 子集不含 {，无需转义
+	}
+	return c, nil
+}
+
+// Payload 返回 GS k m=73 的数据部分（不含长度字节）
+func (c *Code128) Payload() []byte {
+	return c.payload
+}
+
+// Width 返回条码宽度（模块数，不含静区）
+func (c *Code128) Width() int {
+	return 11*(len(c.values)+1) + 13 // 校验符 11 个模块，终止符 13 个模块
+}
+
+func (c *Code128) modules() []bool {
+	return code128Modules(c.values)
+}
+
+// 字符在子集中的码值，不可编码时返回 -1
+// chars 中的 -1 表示 FNC1，各子集均可编码
+func code128Value(c, set int) int {
+	switch {
+	case c < 0:
+		return code128FNC1
+	case set == code128SetA && c < 32:
+		return c + 64
+	case set == code128SetA && c < 96, set == code128SetB && c >= 32:
+		return c - 32
+	}
+	return -1
+}
+
+func isDigitChar(c int) bool {
+	return c >= '0' && c <= '9'
+}
+
+// 动态规划求最短符号序列
+// cost[i][s] 为当前处于子集 s 时编码 chars[i:] 所需的最少符号数
+func planCode128(chars []int) *Code128 {
+	const inf = 1 << 30
+	n := len(chars)
+	direct := make([][3]int, n+1) // 不切换子集，在 s 中编码下一个字符
+	cost := make([][3]int, n+1)
+	next := make([][3]int, n+1) // 最优时编码下一个字符所用的子集
+
+	for i := n - 1; i >= 0; i-- {
+		for s := 0; s < 3; s++ {
+			direct[i][s] = inf
+			switch {
+			case s == code128SetC && chars[i] < 0:
+				direct[i][s] = 1 + cost[i+1][s]
+			case s == code128SetC:
+				if i+1 < n && isDigitChar(chars[i]) && isDigitChar(chars[i+1]) {
+					direct[i][s] = 1 + cost[i+2][s]
+				}
+			case code128Value(chars[i], s) >= 0:
+				direct[i][s] = 1 + cost[i+1][s]
+			default:
+				direct[i][s] = 2 + cost[i+1][s] // SHIFT 临时切换到另一个子集
+			}
+		}
+		for s := 0; s < 3; s++ {
+			cost[i][s], next[i][s] = direct[i][s], s
+			for t := 0; t < 3; t++ {
+				if t != s && 1+direct[i][t] < cost[i][s] {
+					cost[i][s], next[i][s] = 1+direct[i][t], t
+				}
+			}
+		}
+	}
+
+	// 起始子集：优先 B，其次 C、A
+	set := code128SetB
+	for _, s := range []int{code128SetC, code128SetA} {
+		if direct[0][s] < direct[0][set] {
+			set = s
+		}
+	}
+
+	c := &Code128{
+		values:  []int{code128Starts[set]},
+		payload: []byte{'{', code128SetNames[set]},
+	}
+	for i := 0; i < n; {
+		if t := next[i][set]; t != set {
+			set = t
+			c.values = append(c.values, code128Switches[set])
+			c.payload = append(c.payload, '{', code128SetNames[set])
+		}
+
+		ch := chars[i]
+		switch {
+		case ch < 0:
+			c.values = append(c.values, code128FNC1)
+			c.payload = append(c.payload, '{', '1')
+			i++
+		case set == code128SetC:
+			v := (ch-'0')*10 + chars[i+1] - '0'
+			c.values = append(c.values, v)
+			c.payload = append(c.payload, byte(v)) // C 子集每个字节为 0-99 的码值
+			i += 2
+		case code128Value(ch, set) >= 0:
+			c.values = append(c.values, code128Value(ch, set))
+			c.payload = appendCode128Char(c.payload, ch)
+			i++
+		default:
+			c.values = append(c.values, code128Shift, code128Value(ch, 1-set))
+			c.payload = appendCode128Char(append(c.payload, '{', 'S'), ch)
+			i++
+		}
+	}
+	return c
+}
+
+// 追加 A/B 子集字符，字符 { 需转义为 {{
+func appendCode128Char(payload []byte, c int) []byte {
+	if c == '{' {
+		return append(payload, '{', '{')
+	}
+	return append(payload, byte(c))
+}
+
+// ParseCode128Payload 解析打印机 GS k 73 的数据部分（以 {A/{B/{C 开头）
+// 返回的 Text 为打印机 HRI 显示的字符，FNC1 不显示
+func ParseCode128Payload(payload []byte) (*Code, error) {
+	if len(payload) < 2 || payload[0] != '{' || payload[1] < 'A' || payload[1] > 'C' {
+		return nil, fmt.Errorf("CODE128数据必须以 {A、{B 或 {C 开头")
+	}
+	set := int(payload[1] - 'A')
+	values := []int{code128Starts[set]}
+	var text []byte
+	shift := false
+	for i := 2; i < len(payload); i++ {
+		c := int(payload[i])
+		if c == '{' {
+			if i+1 == len(payload) {
+				return nil, fmt.Errorf("CODE128数据第%d个字节：{ 之后缺少字符", i+1)
+			}
+			i++
+			switch e := payload[i]; e {
+			case 'A', 'B', 'C':
+				set = int(e - 'A')
+				values = append(values, code128Switches[set])
+				continue
+			case 'S':
+				if set == code128SetC {
+					return nil, fmt.Errorf("CODE128数据第%d个字节：C 子集不能使用 {S", i)
+				}
+				values = append(values, code128Shift)
+				shift = true
+				continue
+			case '1':
+				values = append(values, code128FNC1)
+				continue
+			case '{':
+				// 转义的 { 字符
+			default:
+				return nil, fmt.Errorf("CODE128数据第%d个字节：不支持的转义 {%c", i, e)
+			}
+		}
+
+		cur := set
+		if shift {
+			cur, shift = 1-set, false
+		}
+		if cur == code128SetC {
+			if c > 99 {
+				return nil, fmt.Errorf("CODE128数据第%d个字节：C 子集的码值必须是 0-99", i+1)
+			}
+			values = append(values, c)
+			text = append(text, byte('0'+c/10), byte('0'+c%10))
+			continue
+		}
+		v := code128Value(c, cur)
+		if v < 0 || c > 127 {
+			return nil, fmt.Errorf("CODE128数据第%d个字节：字符 0x%02X 不在 %c 子集中", i+1, c, code128SetNames[cur])
+		}
+		values = append(values, v)
+		if c < 32 {
+			c = ' ' // 控制字符显示为空格
+		}
+		text = append(text, byte(c))
+	}
+	return &Code{Type: "CODE128", Text: string(text), Modules: code128Modules(values)}, nil
+}
+
+// 编码 CODE128
+func encodeCode128(data string) ([]bool, error) {
+	c, err := EncodeCode128(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.modules(), nil
+}
+
+// 编码 CODE128 A 子集
+func encodeCode128A(data string) ([]bool, error) {
+	c, err := EncodeCode128A(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.modules(), nil
+}
+
+// 由码值序列（含起始符）生成模块，自动追加校验符和终止符
+func code128Modules(values []int) []bool {
+	sum := values[0]
+	for i := 1; i < len(values); i++ {
+		sum += i * values[i]
+	}
+
+	var modules []bool
+	for _, v := range values {
+		modules = appendWidths(modules, code128Patterns[v])
+	}
+	modules = appendWidths(modules, code128Patterns[sum%103])
+	modules = appendWidths(modules, code128Patterns[code128Stop])
+	return modules
+}
@@ -0,0 +1,61 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CODE39 字符集，与 code39Patterns 一一对应
+const code39Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ-. $/+%*"
+
+// CODE39 条空宽窄序列（条空交替共 9 个元素，1 为宽）
+var code39Patterns = [...]string{
+	"000110100", "100100001", "001100001", "101100000", "000110001",
+	"100110000", "001110000", "000100101", "100100100", "001100100",
+	"100001001", "001001001", "101001000", "000011001", "100011000",
+	"001011000", "000001101", "100001100", "001001100", "000011100",
+	"100000011", "001000011", "101000010", "000010011", "100010010",
+	"001010010", "000000111", "100000110", "001000110", "000010110",
+	"110000001", "011000001", "111000000", "010010001", "110010000",
+	"011010000", "010000101", "110000100", "011000100", "010101000",
+	"010100010", "010001010", "000101010", "010010100",
+}
+
+// 编码 CODE39，自动添加起止符 *，宽窄比为 3:1
+func encodeCode39(data string) ([]bool, error) {
+	if data == "" {
+		return nil, fmt.Errorf("CODE39条形码数据不能为空")
+	}
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			return nil, charError("CODE39", data, i, "不支持小写字母，请使用大写")
+		case c == '*':
+			return nil, charError("CODE39", data, i, "* 是起始符和终止符，不能出现在数据中")
+		case strings.IndexByte(code39Chars, c) < 0:
+			return nil, charError("CODE39", data, i, "只支持大写字母、数字和 - . 空格 $ / + %")
+		}
+	}
+
+	var modules []bool
+	for i, c := range "*" + data + "*" {
+		idx := strings.IndexRune(code39Chars, c)
+		if i > 0 {
+			modules = append(modules, false) // 字符间隔
+		}
+		bar := true
+		for _, e := range code39Patterns[idx] {
+			n := 1
+			if e == '1' {
+				n = 3
+			}
+			for j := 0; j < n; j++ {
+				modules = append(modules, bar)
+			}
+			bar = !bar
+		}
+	}
+	return modules, nil
+}
@@ -0,0 +1,105 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CODE93 字符集，最后4个为移位符 ($) (%) (/) (+)，用小写 a-d 表示
+const code93Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ-. $/+%abcd"
+
+// CODE93 各字符的条空宽度（3条3空，共9个模块）
+var code93Patterns = [47]string{
+	"131112", "111213", "111312", "111411", "121113", "121212", "121311", "111114", "131211", "141111",
+	"211113", "211212", "211311", "221112", "221211", "231111", "112113", "112212", "112311", "122112",
+	"132111", "111123", "111222", "111321", "121122", "131121", "212112", "212211", "211122", "211221",
+	"221121", "222111", "112122", "112221", "122121", "123111", "121131", "311112", "311211", "321111",
+	"112131", "113121", "211131", "121221", "312111", "311121", "122211",
+}
+
+// 起始符/终止符 *
+const code93Guard = "111141"
+
+// 将 ASCII 数据转换为 CODE93 基本字符序列，不在基本字符集中的字符使用移位符表示
+func code93Expand(data string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c > 127:
+			return "", charError("CODE93", data, i, "只支持ASCII字符")
+		case c == 0:
+			sb.WriteString("bU")
+		case c <= 26:
+			sb.WriteByte('a')
+			sb.WriteByte('A' + c - 1)
+		case c <= 31:
+			sb.WriteByte('b')
+			sb.WriteByte('A' + c - 27)
+		case c == ' ' || c == '$' || c == '%' || c == '+':
+			sb.WriteByte(c)
+		case c <= ',':
+			sb.WriteByte('c')
+			sb.WriteByte('A' + c - '!')
+		case c <= '9':
+			sb.WriteByte(c) // - . / 和数字
+		case c == ':':
+			sb.WriteString("cZ")
+		case c <= '?':
+			sb.WriteByte('b')
+			sb.WriteByte('F' + c - ';')
+		case c == '@':
+			sb.WriteString("bV")
+		case c <= 'Z':
+			sb.WriteByte(c)
+		case c <= '_':
+			sb.WriteByte('b')
+			sb.WriteByte('K' + c - '[')
+		case c == '`':
+			sb.WriteString("bW")
+		case c <= 'z':
+			sb.WriteByte('d')
+			sb.WriteByte('A' + c - 'a')
+		default:
+			sb.WriteByte('b')
+			sb.WriteByte('P' + c - '{')
+		}
+	}
+	return sb.String(), nil
+}
+
+// 计算 CODE93 校验字符，权重从右向左为 1..maxWeight 循环
+func code93CheckValue(values []int, maxWeight int) int {
+	sum := 0
+	for i := len(values) - 1; i >= 0; i-- {
+		weight := (len(values)-1-i)%maxWeight + 1
+		sum += values[i] * weight
+	}
+	return sum % 47
+}
+
+// 编码 CODE93（全ASCII），自动追加 C、K 两个校验字符
+func encodeCode93(data string) ([]bool, error) {
+	if data == "" {
+		return nil, fmt.Errorf("CODE93条形码数据不能为空")
+	}
+	expanded, err := code93Expand(data)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]int, 0, len(expanded)+2)
+	for i := 0; i < len(expanded); i++ {
+		values = append(values, strings.IndexByte(code93Chars, expanded[i]))
+	}
+	values = append(values, code93CheckValue(values, 20)) // C
+	values = append(values, code93CheckValue(values, 15)) // K
+
+	modules := appendWidths(nil, code93Guard)
+	for _, v := range values {
+		modules = appendWidths(modules, code93Patterns[v])
+	}
+	modules = appendWidths(modules, code93Guard)
+	modules = append(modules, true) // 终止条
+	return modules, nil
+}
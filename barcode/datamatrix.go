@@ -0,0 +1,539 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Data Matrix 形状
+const (
+	DataMatrixAuto      = iota // 自动选择面积最小的符号
+	DataMatrixSquare           // 仅正方形
+	DataMatrixRectangle        // 仅长方形
+)
+
+// ParseDataMatrixShape 解析形状字符串 square/rectangle/auto，空字符串为 square
+func ParseDataMatrixShape(shape string) (int, error) {
+	switch strings.ToLower(shape) {
+	case "", "square":
+		return DataMatrixSquare, nil
+	case "rectangle", "rect":
+		return DataMatrixRectangle, nil
+	case "auto":
+		return DataMatrixAuto, nil
+	default:
+		return 0, fmt.Errorf("Data Matrix形状必须是 square、rectangle 或 auto: %s", shape)
+	}
+}
+
+// dataMatrixSymbol ECC200 符号规格
+type dataMatrixSymbol struct {
+	rows, cols       int // 符号尺寸（含定位图形）
+	regionH, regionW int // 单个数据区尺寸
+	dataCodewords    int
+	eccCodewords     int // 纠错码字总数
+	blocks           int // 交错块数
+}
+
+// ECC200 符号规格表，按容量从小到大排列
+var dataMatrixSymbols = []dataMatrixSymbol{
+	{10, 10, 8, 8, 3, 5, 1},
+	{12, 12, 10, 10, 5, 7, 1},
+	{8, 18, 6, 16, 5, 7, 1},
+	{14, 14, 12, 12, 8, 10, 1},
+	{8, 32, 6, 14, 10, 11, 1},
+	{16, 16, 14, 14, 12, 12, 1},
+	{12, 26, 10, 24, 16, 14, 1},
+	{18, 18, 16, 16, 18, 14, 1},
+	{20, 20, 18, 18, 22, 18, 1},
+	{12, 36, 10, 16, 22, 18, 1},
+	{22, 22, 20, 20, 30, 20, 1},
+	{16, 36, 14, 16, 32, 24, 1},
+	{24, 24, 22, 22, 36, 24, 1},
+	{26, 26, 24, 24, 44, 28, 1},
+	{16, 48, 14, 22, 49, 28, 1},
+	{32, 32, 14, 14, 62, 36, 1},
+	{36, 36, 16, 16, 86, 42, 1},
+	{40, 40, 18, 18, 114, 48, 1},
+	{44, 44, 20, 20, 144, 56, 1},
+	{48, 48, 22, 22, 174, 68, 1},
+	{52, 52, 24, 24, 204, 84, 2},
+	{64, 64, 14, 14, 280, 112, 2},
+	{72, 72, 16, 16, 368, 144, 4},
+	{80, 80, 18, 18, 456, 192, 4},
+	{88, 88, 20, 20, 576, 224, 4},
+	{96, 96, 22, 22, 696, 272, 4},
+	{104, 104, 24, 24, 816, 336, 6},
+	{120, 120, 18, 18, 1050, 408, 6},
+	{132, 132, 20, 20, 1304, 496, 8},
+	{144, 144, 22, 22, 1558, 620, 10},
+}
+
+// Data Matrix 特殊码字
+const (
+	dmPad         = 129
+	dmLatchC40    = 230
+	dmLatchBase   = 231
+	dmFNC1        = 232
+	dmUpperShift  = 235
+	dmLatchText   = 239
+	dmUnlatch     = 254
+	dmFNC1Message = -1 // 消息中表示 FNC1 的占位值
+)
+
+// EncodeDataMatrix 编码 ECC200 Data Matrix，返回每个模块一个像素、不含静区的矩阵
+// gs1 为 true 时数据为 (AI)数据 格式，校验规则和 FNC1 分隔符与 GS1-128 相同
+func EncodeDataMatrix(data string, shape int, gs1 bool) (*Bitmap, error) {
+	if data == "" {
+		return nil, fmt.Errorf("Data Matrix数据不能为空")
+	}
+
+	message, err := dataMatrixMessage(data, gs1)
+	if err != nil {
+		return nil, err
+	}
+
+	codewords := dmEncodeMessage(message, gs1)
+
+	// 选择能容纳数据的最小符号
+	var symbol *dataMatrixSymbol
+	for i := range dataMatrixSymbols {
+		s := &dataMatrixSymbols[i]
+		square := s.rows == s.cols
+		if (shape == DataMatrixSquare && !square) || (shape == DataMatrixRectangle && square) {
+			continue
+		}
+		need := len(codewords)
+		// 数据恰好填满符号时，C40/Text 末尾的返回 ASCII 码字可省略
+		if need > 0 && codewords[need-1] == dmUnlatch && need-1 == s.dataCodewords {
+			need--
+		}
+		if need <= s.dataCodewords {
+			symbol = s
+			codewords = codewords[:need]
+			break
+		}
+	}
+	if symbol == nil {
+		limit := dataMatrixSymbols[len(dataMatrixSymbols)-1].dataCodewords
+		if shape == DataMatrixRectangle {
+			limit = 49
+		}
+		return nil, fmt.Errorf("Data Matrix数据过长：需要%d个码字，最多%d个", len(codewords), limit)
+	}
+
+	codewords = dmPadCodewords(codewords, symbol.dataCodewords)
+	codewords = dmAddECC(codewords, symbol)
+	return dmPlaceSymbol(codewords, symbol), nil
+}
+
+// 将数据转换为消息序列，FNC1 以 dmFNC1Message 表示
+// GS1 数据按 GS1-128 的规则解析校验：以 FNC1 开头，可变长度 AI 之后插入 FNC1 分隔符
+func dataMatrixMessage(data string, gs1 bool) ([]int, error) {
+	var message []int
+	if !gs1 {
+		for i := 0; i < len(data); i++ {
+			message = append(message, int(data[i]))
+		}
+		return message, nil
+	}
+
+	elements, err := ParseGS1Elements(data)
+	if err != nil {
+		return nil, err
+	}
+	message = append(message, dmFNC1Message)
+	for i, e := range elements {
+		for _, s := range []string{e.AI, e.Value} {
+			for j := 0; j < len(s); j++ {
+				message = append(message, int(s[j]))
+			}
+		}
+		if i < len(elements)-1 && gs1NeedsSeparator(e.AI) {
+			message = append(message, dmFNC1Message)
+		}
+	}
+	return message, nil
+}
+
+// 分别尝试各种编码方式，取码字最少者
+// GS1 符号的首个码字必须是 ASCII 的 FNC1（232），因此开头的 FNC1 单独输出，其余部分再参与比较
+func dmEncodeMessage(message []int, gs1 bool) []int {
+	var prefix []int
+	if gs1 {
+		prefix, message = []int{dmFNC1}, message[1:]
+	}
+
+	candidates := [][]int{dmEncodeASCII(message)}
+	if cw, ok := dmEncodeC40(message, false); ok {
+		candidates = append(candidates, cw)
+	}
+	if cw, ok := dmEncodeC40(message, true); ok {
+		candidates = append(candidates, cw)
+	}
+	if !gs1 {
+		candidates = append(candidates, dmEncodeBase256(message))
+	}
+	codewords := candidates[0]
+	for _, cw := range candidates[1:] {
+		if len(cw) < len(codewords) {
+			codewords = cw
+		}
+	}
+	return append(prefix, codewords...)
+}
+
+// ASCII 编码：数字对压缩为一个码字
+func dmEncodeASCII(message []int) []int {
+	var cw []int
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		switch {
+		case c == dmFNC1Message:
+			cw = append(cw, dmFNC1)
+		case isDigit(c) && i+1 < len(message) && isDigit(message[i+1]):
+			cw = append(cw, 130+(c-'0')*10+(message[i+1]-'0'))
+			i++
+		case c < 128:
+			cw = append(cw, c+1)
+		default:
+			cw = append(cw, dmUpperShift, c-128+1)
+		}
+	}
+	return cw
+}
+
+func isDigit(c int) bool {
+	return c >= '0' && c <= '9'
+}
+
+// C40/Text 单个字符的值序列
+func dmC40Values(c int, text bool) []int {
+	if c == dmFNC1Message {
+		return []int{1, 27}
+	}
+	if c > 127 {
+		return append([]int{1, 30}, dmC40Values(c-128, text)...)
+	}
+
+	basic := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	shift3 := "`abcdefghijklmnopqrstuvwxyz{|}~\x7f"
+	if text {
+		basic = "abcdefghijklmnopqrstuvwxyz"
+		shift3 = "`ABCDEFGHIJKLMNOPQRSTUVWXYZ{|}~\x7f"
+	}
+	switch {
+	case c == ' ':
+		return []int{3}
+	case isDigit(c):
+		return []int{c - '0' + 4}
+	case strings.IndexByte(basic, byte(c)) >= 0:
+		return []int{strings.IndexByte(basic, byte(c)) + 14}
+	case c < 32:
+		return []int{0, c}
+	case text && c >= 'A' && c <= 'Z':
+		// Text 模式的大写字母在 shift 3 中，必须先于标点判断
+		return []int{2, c - 'A' + 1}
+	case c <= 47:
+		return []int{1, c - 33}
+	case c <= 64:
+		return []int{1, c - 58 + 15}
+	case c <= 95:
+		return []int{1, c - 91 + 22}
+	default:
+		return []int{2, strings.IndexByte(shift3, byte(c))}
+	}
+}
+
+// C40/Text 编码：每三个值压缩为两个码字，不足三个值的尾部字符改用 ASCII 编码
+func dmEncodeC40(message []int, text bool) ([]int, bool) {
+	var values []int
+	boundary := 0 // 值个数为 3 的倍数时对应的字符数
+	for i, c := range message {
+		values = append(values, dmC40Values(c, text)...)
+		if len(values)%3 == 0 {
+			boundary = i + 1
+		}
+	}
+	if boundary == 0 {
+		return nil, false
+	}
+
+	values = values[:0]
+	for _, c := range message[:boundary] {
+		values = append(values, dmC40Values(c, text)...)
+	}
+
+	cw := []int{dmLatchC40}
+	if text {
+		cw[0] = dmLatchText
+	}
+	for i := 0; i < len(values); i += 3 {
+		v := 1600*values[i] + 40*values[i+1] + values[i+2] + 1
+		cw = append(cw, v/256, v%256)
+	}
+
+	if boundary < len(message) {
+		cw = append(cw, dmUnlatch)
+		cw = append(cw, dmEncodeASCII(message[boundary:])...)
+	} else {
+		cw = append(cw, dmUnlatch)
+	}
+	return cw, true
+}
+
+// Base256 编码：锁定码字 + 长度 + 原始字节，均经过 255 状态随机化
+func dmEncodeBase256(message []int) []int {
+	n := len(message)
+	field := []int{n}
+	if n > 249 {
+		field = []int{n/250 + 249, n % 250}
+	}
+	field = append(field, message...)
+
+	cw := []int{dmLatchBase}
+	for _, v := range field {
+		pos := len(cw) + 1
+		pseudo := (149*pos)%255 + 1
+		tmp := v + pseudo
+		if tmp > 255 {
+			tmp -= 256
+		}
+		cw = append(cw, tmp)
+	}
+	return cw
+}
+
+// 补齐数据码字：首个填充为 129，其余经 253 状态随机化
+func dmPadCodewords(cw []int, capacity int) []int {
+	if len(cw) < capacity {
+		cw = append(cw, dmPad)
+	}
+	for len(cw) < capacity {
+		pos := len(cw) + 1
+		tmp := dmPad + (149*pos)%253 + 1
+		if tmp > 254 {
+			tmp -= 254
+		}
+		cw = append(cw, tmp)
+	}
+	return cw
+}
+
+// 按块计算纠错码字并交错排列
+func dmAddECC(data []int, s *dataMatrixSymbol) []int {
+	eccPerBlock := s.eccCodewords / s.blocks
+	gen := dataMatrixField.generator(eccPerBlock, 1)
+
+	result := make([]int, s.dataCodewords+s.eccCodewords)
+	copy(result, data)
+	for b := 0; b < s.blocks; b++ {
+		var block []int
+		for i := b; i < s.dataCodewords; i += s.blocks {
+			block = append(block, data[i])
+		}
+		// 144x144 符号的纠错码字从第 9 块开始交错
+		slot := b
+		if s.rows == 144 {
+			slot = (b + 2) % s.blocks
+		}
+		for i, e := range dataMatrixField.ecc(block, gen) {
+			result[s.dataCodewords+slot+i*s.blocks] = e
+		}
+	}
+	return result
+}
+
+// dmPlacement 码字在映射矩阵中的放置（ECC200 附录 F）
+type dmPlacement struct {
+	rows, cols int
+	codewords  []int
+	bits       []int8 // -1 未放置，0 浅，1 深
+}
+
+func (p *dmPlacement) has(row, col int) bool {
+	return p.bits[row*p.cols+col] >= 0
+}
+
+func (p *dmPlacement) module(row, col, pos, bit int) {
+	if row < 0 {
+		row += p.rows
+		col += 4 - (p.rows+4)%8
+	}
+	if col < 0 {
+		col += p.cols
+		row += 4 - (p.cols+4)%8
+	}
+	var v int8
+	if p.codewords[pos]&(1<<uint(8-bit)) != 0 {
+		v = 1
+	}
+	p.bits[row*p.cols+col] = v
+}
+
+func (p *dmPlacement) utah(row, col, pos int) {
+	p.module(row-2, col-2, pos, 1)
+	p.module(row-2, col-1, pos, 2)
+	p.module(row-1, col-2, pos, 3)
+	p.module(row-1, col-1, pos, 4)
+	p.module(row-1, col, pos, 5)
+	p.module(row, col-2, pos, 6)
+	p.module(row, col-1, pos, 7)
+	p.module(row, col, pos, 8)
+}
+
+func (p *dmPlacement) corner1(pos int) {
+	p.module(p.rows-1, 0, pos, 1)
+	p.module(p.rows-1, 1, pos, 2)
+	p.module(p.rows-1, 2, pos, 3)
+	p.module(0, p.cols-2, pos, 4)
+	p.module(0, p.cols-1, pos, 5)
+	p.module(1, p.cols-1, pos, 6)
+	p.module(2, p.cols-1, pos, 7)
+	p.module(3, p.cols-1, pos, 8)
+}
+
+func (p *dmPlacement) corner2(pos int) {
+	p.module(p.rows-3, 0, pos, 1)
+	p.module(p.rows-2, 0, pos, 2)
+	p.module(p.rows-1, 0, pos, 3)
+	p.module(0, p.cols-4, pos, 4)
+	p.module(0, p.cols-3, pos, 5)
+	p.module(0, p.cols-2, pos, 6)
+	p.module(0, p.cols-1, pos, 7)
+	p.module(1, p.cols-1, pos, 8)
+}
+
+func (p *dmPlacement) corner3(pos int) {
+	p.module(p.rows-3, 0, pos, 1)
+	p.module(p.rows-2, 0, pos, 2)
+	p.module(p.rows-1, 0, pos, 3)
+	p.module(0, p.cols-2, pos, 4)
+	p.module(0, p.cols-1, pos, 5)
+	p.module(1, p.cols-1, pos, 6)
+	p.module(2, p.cols-1, pos, 7)
+	p.module(3, p.cols-1, pos, 8)
+}
+
+func (p *dmPlacement) corner4(pos int) {
+	p.module(p.rows-1, 0, pos, 1)
+	p.module(p.rows-1, p.cols-1, pos, 2)
+	p.module(0, p.cols-3, pos, 3)
+	p.module(0, p.cols-2, pos, 4)
+	p.module(0, p.cols-1, pos, 5)
+	p.module(1, p.cols-3, pos, 6)
+	p.module(1, p.cols-2, pos, 7)
+	p.module(1, p.cols-1, pos, 8)
+}
+
+func (p *dmPlacement) place() {
+	pos, row, col := 0, 4, 0
+	for {
+		if row == p.rows && col == 0 {
+			p.corner1(pos)
+			pos++
+		}
+		if row == p.rows-2 && col == 0 && p.cols%4 != 0 {
+			p.corner2(pos)
+			pos++
+		}
+		if row == p.rows-2 && col == 0 && p.cols%8 == 4 {
+			p.corner3(pos)
+			pos++
+		}
+		if row == p.rows+4 && col == 2 && p.cols%8 == 0 {
+			p.corner4(pos)
+			pos++
+		}
+
+		// 向右上斜向扫描
+		for {
+			if row < p.rows && col >= 0 && !p.has(row, col) {
+				p.utah(row, col, pos)
+				pos++
+			}
+			row -= 2
+			col += 2
+			if row < 0 || col >= p.cols {
+				break
+			}
+		}
+		row++
+		col += 3
+
+		// 向左下斜向扫描
+		for {
+			if row >= 0 && col < p.cols && !p.has(row, col) {
+				p.utah(row, col, pos)
+				pos++
+			}
+			row += 2
+			col -= 2
+			if row >= p.rows || col < 0 {
+				break
+			}
+		}
+		row += 3
+		col++
+
+		if row >= p.rows && col >= p.cols {
+			break
+		}
+	}
+
+	// 右下角未使用的模块固定为棋盘图案
+	if !p.has(p.rows-1, p.cols-1) {
+		p.bits[(p.rows-1)*p.cols+p.cols-1] = 1
+		p.bits[(p.rows-2)*p.cols+p.cols-2] = 1
+	}
+}
+
+// 放置码字并添加定位图形和时钟图形
+func dmPlaceSymbol(codewords []int, s *dataMatrixSymbol) *Bitmap {
+	regionsY := (s.rows) / (s.regionH + 2)
+	regionsX := (s.cols) / (s.regionW + 2)
+	p := &dmPlacement{
+		rows:      regionsY * s.regionH,
+		cols:      regionsX * s.regionW,
+		codewords: codewords,
+	}
+	p.bits = make([]int8, p.rows*p.cols)
+	for i := range p.bits {
+		p.bits[i] = -1
+	}
+	p.place()
+
+	bm := NewBitmap(s.cols, s.rows)
+	outY := 0
+	for y := 0; y < p.rows; y++ {
+		if y%s.regionH == 0 {
+			// 数据区上边：时钟图形
+			for x := 0; x < s.cols; x++ {
+				bm.Set(x, outY, x%2 == 0)
+			}
+			outY++
+		}
+		outX := 0
+		for x := 0; x < p.cols; x++ {
+			if x%s.regionW == 0 {
+				bm.Set(outX, outY, true) // 左边：实线
+				outX++
+			}
+			bm.Set(outX, outY, p.bits[y*p.cols+x] == 1)
+			outX++
+			if x%s.regionW == s.regionW-1 {
+				bm.Set(outX, outY, y%2 == 0) // 右边：时钟图形
+				outX++
+			}
+		}
+		outY++
+		if y%s.regionH == s.regionH-1 {
+			// 数据区下边：实线
+			for x := 0; x < s.cols; x++ {
+				bm.Set(x, outY, true)
+			}
+			outY++
+		}
+	}
+	return bm
+}
@@ -0,0 +1,218 @@
+package barcode
+
+import (
+	"strings"
+	"testing"
+)
+
+// ISO/IEC 16022 示例：123456 编码为 10x10 符号
+func TestDataMatrixCodewords(t *testing.T) {
+	s := &dataMatrixSymbols[0]
+	message, _ := dataMatrixMessage("123456", false)
+	got := dmAddECC(dmEncodeASCII(message), s)
+	want := []int{142, 164, 186, 114, 25, 5, 88, 102}
+	if len(got) != len(want) {
+		t.Fatalf("码字为 %v，应为 %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("码字为 %v，应为 %v", got, want)
+		}
+	}
+
+	bm, err := EncodeDataMatrix("123456", DataMatrixAuto, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bm.Width != 10 || bm.Height != 10 {
+		t.Fatalf("符号为 %dx%d，应为 10x10", bm.Width, bm.Height)
+	}
+	// 左边和下边为实线，上边和右边为交替的定时图形
+	for i := 0; i < 10; i++ {
+		if !bm.Get(0, i) || !bm.Get(i, 9) {
+			t.Errorf("L 形定位图形第 %d 个模块应为深色", i)
+		}
+		if bm.Get(i, 0) != (i%2 == 0) || bm.Get(9, i) != (i%2 == 1) {
+			t.Errorf("定时图形第 %d 个模块错误", i)
+		}
+	}
+}
+
+// 补齐码字：首个为 129，其余按位置随机化
+func TestDataMatrixPadding(t *testing.T) {
+	got := dmPadCodewords([]int{66}, 5)
+	want := []int{66, 129, 70, 220, 115}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("补齐后为 %v，应为 %v", got, want)
+		}
+	}
+}
+
+// GS1 数据与 GS1-128 使用相同的解析校验：定长 AI 之后没有分隔符，值中的 ( 不开始新的 AI
+func TestDataMatrixGS1Message(t *testing.T) {
+	cases := []struct {
+		data string
+		want []int
+	}{
+		{"(01)09501101530003(10)AB", []int{dmFNC1, 131, 139, 180, 141, 131, 183, 130, 133, 140, 66, 67}},
+		{"(10)A(B(21)1", []int{dmFNC1, 140, 66, 41, 67, dmFNC1, 151, 50}},
+	}
+	for _, tc := range cases {
+		message, err := dataMatrixMessage(tc.data, true)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.data, err)
+		}
+		cw := dmEncodeASCII(message)
+		if len(cw) != len(tc.want) {
+			t.Fatalf("%s 的码字为 %v，应为 %v", tc.data, cw, tc.want)
+		}
+		for i := range cw {
+			if cw[i] != tc.want[i] {
+				t.Fatalf("%s 的码字为 %v，应为 %v", tc.data, cw, tc.want)
+			}
+		}
+	}
+
+	for _, data := range []string{"(01)09501101530004", "01095011015300", "(99)", "(10)A\x1DB"} {
+		if _, err := EncodeDataMatrix(data, DataMatrixAuto, true); err == nil {
+			t.Errorf("无效的 GS1 数据 %q 应返回错误", data)
+		}
+	}
+	// 不受 GS1-128 的 48 字符限制
+	long := "(01)09501101530003(21)" + strings.Repeat("X", 20) + "(240)" + strings.Repeat("Y", 30)
+	if _, err := EncodeDataMatrix(long, DataMatrixAuto, true); err != nil {
+		t.Errorf("GS1 Data Matrix 数据超过48个字符时应能编码: %v", err)
+	}
+}
+
+// 将码字解码回消息序列，用于验证 ASCII/C40/Text 编码的往返结果
+func dmDecode(cw []int) []int {
+	var message []int
+	upper := 0
+	emit := func(c int) {
+		message = append(message, c+upper)
+		upper = 0
+	}
+	for i := 0; i < len(cw); i++ {
+		c := cw[i]
+		switch {
+		case c == dmPad:
+			return message
+		case c <= 128:
+			emit(c - 1)
+		case c < dmLatchC40:
+			emit('0' + (c-130)/10)
+			emit('0' + (c-130)%10)
+		case c == dmFNC1:
+			message = append(message, dmFNC1Message)
+		case c == dmUpperShift:
+			upper = 128
+		case c == dmLatchC40 || c == dmLatchText:
+			basic := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+			shift3 := "`abcdefghijklmnopqrstuvwxyz{|}~\x7f"
+			if c == dmLatchText {
+				basic = "abcdefghijklmnopqrstuvwxyz"
+				shift3 = "`ABCDEFGHIJKLMNOPQRSTUVWXYZ{|}~\x7f"
+			}
+			shift := 0
+			for i++; i+1 < len(cw) && cw[i] != dmUnlatch; i += 2 {
+				v := cw[i]*256 + cw[i+1] - 1
+				for _, x := range []int{v / 1600, v / 40 % 40, v % 40} {
+					switch {
+					case shift == 1:
+						emit(x)
+					case shift == 2 && x == 27:
+						message = append(message, dmFNC1Message)
+					case shift == 2 && x == 30:
+						upper = 128
+					case shift == 2:
+						emit(int("!\"#$%&'()*+,-./:;<=>?@[\\]^_"[x]))
+					case shift == 3:
+						emit(int(shift3[x]))
+					case x < 3:
+						shift = x + 1
+						continue
+					case x == 3:
+						emit(' ')
+					case x < 14:
+						emit('0' + x - 4)
+					default:
+						emit(int(basic[x-14]))
+					}
+					shift = 0
+				}
+			}
+		}
+	}
+	return message
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// C40 和 Text 编码解码后应与原数据一致，包括大小写字母、数字、标点和控制字符
+func TestDataMatrixC40TextRoundTrip(t *testing.T) {
+	for _, data := range []string{
+		"Data Matrix Test 2024",
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		"abcdefghijklmnopqrstuvwxyz",
+		"Hello, World! [x]_~`{|}@#\t",
+		"MIXED case TEXT 123 ab",
+	} {
+		message, _ := dataMatrixMessage(data, false)
+		for _, text := range []bool{false, true} {
+			cw, ok := dmEncodeC40(message, text)
+			if !ok {
+				t.Fatalf("%q 应能以 C40/Text 编码", data)
+			}
+			if got := dmDecode(cw); !equalInts(got, message) {
+				t.Errorf("%q 以 text=%v 编码后解码为 %q", data, text, string(runes(got)))
+			}
+		}
+	}
+
+	// 大小写混合的普通文本选用 Text 编码，比 ASCII 少一个码字
+	message, _ := dataMatrixMessage("Data Matrix Test 2024", false)
+	cw := dmEncodeMessage(message, false)
+	if cw[0] != dmLatchText || len(cw) != 18 {
+		t.Errorf("码字为 %v，应以 Text 编码为 18 个码字", cw)
+	}
+	if got := dmDecode(cw); !equalInts(got, message) {
+		t.Errorf("解码结果为 %q", string(runes(got)))
+	}
+}
+
+// GS1 Data Matrix 的首个码字必须是 FNC1，即使其余部分使用 C40 编码
+func TestDataMatrixGS1LeadingFNC1(t *testing.T) {
+	for _, data := range []string{"(21)ABCDEFGHIJKLMNOPQRST", "(10)abcdefghijklmnop(21)XYZ"} {
+		message, err := dataMatrixMessage(data, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cw := dmEncodeMessage(message, true)
+		if cw[0] != dmFNC1 {
+			t.Errorf("%s 的首个码字为 %d，应为 232", data, cw[0])
+		}
+		if got := dmDecode(cw); !equalInts(got, message) {
+			t.Errorf("%s 解码为 %v，应为 %v", data, got, message)
+		}
+	}
+}
+
+func runes(message []int) []rune {
+	r := make([]rune, len(message))
+	for i, c := range message {
+		r[i] = rune(c)
+	}
+	return r
+}
@@ -0,0 +1,106 @@
+package barcode
+
+import (
+	"fmt"
+)
+
+// EAN 数字编码（L 为奇校验，G 为偶校验，R 为右侧）
+var (
+	eanL = [10]string{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"}
+	eanG = [10]string{"0100111", "0110011", "0011011", "0100001", "0011101", "0111001", "0000101", "0010001", "0001001", "0010111"}
+	eanR = [10]string{"1110010", "1100110", "1101100", "1000010", "1011100", "1001110", "1010000", "1000100", "1001000", "1110100"}
+)
+
+// EAN-13 首位数字决定左侧 6 位的奇偶排列（0 为 L，1 为 G）
+var ean13Parity = [10]string{"000000", "001011", "001101", "001110", "010011", "011001", "011100", "010101", "010110", "011010"}
+
+// EANCheckDigit 计算 EAN/UPC 系列校验位（从右向左奇数位乘 3）
+func EANCheckDigit(digits string) int {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			sum += d * 3
+		} else {
+			sum += d
+		}
+	}
+	return (10 - sum%10) % 10
+}
+
+// 检查是否全为数字
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// 补全或校验末位校验码，data 为 n-1 位时补校验位，n 位时校验最后一位
+func eanComplete(barcodeType, data string, n int) (string, error) {
+	if len(data) == n-1 {
+		return data + fmt.Sprintf("%d", EANCheckDigit(data)), nil
+	}
+	if err := checkCheckDigit(barcodeType, data); err != nil {
+		return "", err
+	}
+	return data, nil
+}
+
+// 编码 EAN-13，12 位时自动补校验位，13 位时校验第 13 位
+func encodeEAN13(data string) ([]bool, string, error) {
+	if err := checkDigits("EAN13", data); err != nil {
+		return nil, "", err
+	}
+	if len(data) != 12 && len(data) != 13 {
+		return nil, "", fmt.Errorf("EAN13条形码必须是12或13位数字")
+	}
+	code, err := eanComplete("EAN13", data, 13)
+	if err != nil {
+		return nil, "", err
+	}
+
+	modules := appendBits(nil, "101")
+	parity := ean13Parity[code[0]-'0']
+	for i := 1; i <= 6; i++ {
+		d := code[i] - '0'
+		if parity[i-1] == '1' {
+			modules = appendBits(modules, eanG[d])
+		} else {
+			modules = appendBits(modules, eanL[d])
+		}
+	}
+	modules = appendBits(modules, "01010")
+	for i := 7; i <= 12; i++ {
+		modules = appendBits(modules, eanR[code[i]-'0'])
+	}
+	modules = appendBits(modules, "101")
+	return modules, code, nil
+}
+
+// 编码 EAN-8，7 位时自动补校验位，8 位时校验第 8 位
+func encodeEAN8(data string) ([]bool, string, error) {
+	if err := checkDigits("EAN8", data); err != nil {
+		return nil, "", err
+	}
+	if len(data) != 7 && len(data) != 8 {
+		return nil, "", fmt.Errorf("EAN8条形码必须是7或8位数字")
+	}
+	code, err := eanComplete("EAN8", data, 8)
+	if err != nil {
+		return nil, "", err
+	}
+
+	modules := appendBits(nil, "101")
+	for i := 0; i < 4; i++ {
+		modules = appendBits(modules, eanL[code[i]-'0'])
+	}
+	modules = appendBits(modules, "01010")
+	for i := 4; i < 8; i++ {
+		modules = appendBits(modules, eanR[code[i]-'0'])
+	}
+	modules = appendBits(modules, "101")
+	return modules, code, nil
+}
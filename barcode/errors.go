@@ -0,0 +1,60 @@
+package barcode
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// DataError 条码数据错误
+// Position 大于 0 时指出第几个字符无效（按字符计数，从 1 开始）
+type DataError struct {
+	BarcodeType string `json:"barcodeType"`         // 条码类型
+	Position    int    `json:"position,omitempty"`  // 无效字符的位置，0 表示数据整体有误
+	Character   string `json:"character,omitempty"` // 无效字符
+	Reason      string `json:"reason"`              // 原因
+}
+
+func (e *DataError) Error() string {
+	if e.Position > 0 {
+		r, _ := utf8.DecodeRuneInString(e.Character)
+		return fmt.Sprintf("%s第%d个字符 %s 无效：%s", e.BarcodeType, e.Position, strconv.QuoteRune(r), e.Reason)
+	}
+	return e.Reason
+}
+
+// ErrorCode 错误代码
+func (e *DataError) ErrorCode() string {
+	return "INVALID_DATA"
+}
+
+// 字符错误，i 为 data 中的字节下标
+func charError(barcodeType, data string, i int, reason string) *DataError {
+	r, _ := utf8.DecodeRuneInString(data[i:])
+	return &DataError{
+		BarcodeType: barcodeType,
+		Position:    utf8.RuneCountInString(data[:i]) + 1,
+		Character:   string(r),
+		Reason:      reason,
+	}
+}
+
+// 检查是否全为数字，返回第一个非数字字符的错误
+func checkDigits(barcodeType, data string) error {
+	for i := 0; i < len(data); i++ {
+		if data[i] < '0' || data[i] > '9' {
+			return charError(barcodeType, data, i, "只能是数字")
+		}
+	}
+	return nil
+}
+
+// 校验最后一位 EAN/UPC 校验码
+func checkCheckDigit(barcodeType, data string) error {
+	n := len(data) - 1
+	want := EANCheckDigit(data[:n])
+	if int(data[n]-'0') != want {
+		return charError(barcodeType, data, n, fmt.Sprintf("校验码应为 %d", want))
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package barcode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GS1Element 一个 GS1 应用标识符（AI）及其数据
+type GS1Element struct {
+	AI    string
+	Value string
+}
+
+// GS1 应用标识符的数据格式
+type gs1Format struct {
+	numeric  bool // 只允许数字，否则为 GS1 字符集（82个字符）
+	min, max int  // 数据长度范围
+	check    bool // 最后一位为 GS1 校验码
+	date     bool // 日期 YYMMDD
+}
+
+var gs1Date = gs1Format{numeric: true, min: 6, max: 6, date: true}
+
+// 定长数字
+func gs1Fixed(n int) gs1Format { return gs1Format{numeric: true, min: n, max: n} }
+
+// 定长数字，末位为校验码
+func gs1Check(n int) gs1Format { return gs1Format{numeric: true, min: n, max: n, check: true} }
+
+// 变长数字，最多 n 位
+func gs1Num(n int) gs1Format { return gs1Format{numeric: true, min: 1, max: n} }
+
+// 变长字母数字，最多 n 位
+func gs1Alnum(n int) gs1Format { return gs1Format{min: 1, max: n} }
+
+// 常用应用标识符
+var gs1AIs = map[string]gs1Format{
+	"00": gs1Check(18), // SSCC
+	"01": gs1Check(14), // GTIN
+	"02": gs1Check(14), // 所含贸易项目的 GTIN
+	"10": gs1Alnum(20), // 批号
+	"11": gs1Date,      // 生产日期
+	"12": gs1Date,      // 付款截止日期
+	"13": gs1Date,      // 包装日期
+	"15": gs1Date,      // 保质期
+	"16": gs1Date,      // 销售截止日期
+	"17": gs1Date,      // 有效期
+	"20": gs1Fixed(2),  // 内部产品变体
+	"21": gs1Alnum(20), // 序列号
+	"22": gs1Alnum(20), // 消费品变体
+	"30": gs1Num(8),    // 可变数量
+	"37": gs1Num(8),    // 所含贸易项目数量
+
+	"240": gs1Alnum(30), // 附加产品标识
+	"241": gs1Alnum(30), // 客户部件号
+	"250": gs1Alnum(30), // 二级序列号
+	"251": gs1Alnum(30), // 源实体参考
+	"400": gs1Alnum(30), // 客户订单号
+	"401": gs1Alnum(30), // 全球货物托运标识
+	"402": gs1Check(17), // 全球装运标识
+	"403": gs1Alnum(30), // 路径代码
+	"410": gs1Check(13), // 交货地 GLN
+	"411": gs1Check(13), // 受票方 GLN
+	"412": gs1Check(13), // 供货方 GLN
+	"413": gs1Check(13), // 最终收货方 GLN
+	"414": gs1Check(13), // 位置 GLN
+	"415": gs1Check(13), // 开票方 GLN
+	"416": gs1Check(13), // 生产或服务地 GLN
+	"417": gs1Check(13), // 参与方 GLN
+	"420": gs1Alnum(20), // 交货地邮编
+	"422": gs1Fixed(3),  // 原产国
+	"424": gs1Fixed(3),  // 加工国
+	"426": gs1Fixed(3),  // 全程加工国
+
+	"7003": gs1Fixed(10), // 有效期和时间
+	"8005": gs1Fixed(6),  // 单价
+	"8020": gs1Alnum(25), // 付款单号
+	"90":   gs1Alnum(30), // 贸易伙伴间约定信息
+}
+
+// 查找应用标识符的格式
+func lookupGS1AI(ai string) (gs1Format, bool) {
+	if f, ok := gs1AIs[ai]; ok {
+		return f, true
+	}
+	if len(ai) == 2 && ai >= "91" && ai <= "99" {
+		return gs1Alnum(90), true // 企业内部信息
+	}
+	if len(ai) == 4 && allDigits(ai) {
+		switch {
+		case ai[:2] >= "31" && ai[:2] <= "36":
+			return gs1Fixed(6), true // 计量单位（第4位为小数位数）
+		case ai[:3] == "390" || ai[:3] == "392":
+			return gs1Num(15), true // 应付金额
+		}
+	}
+	return gs1Format{}, false
+}
+
+// 预定义长度的 AI 前缀，其后不需要 FNC1 分隔符
+var gs1PredefinedLength = []string{
+	"00", "01", "02", "03", "04", "11", "12", "13", "14", "15", "16", "17", "18", "19", "20",
+	"31", "32", "33", "34", "35", "36", "41",
+}
+
+func gs1NeedsSeparator(ai string) bool {
+	for _, p := range gs1PredefinedLength {
+		if strings.HasPrefix(ai, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// GS1-128 最多 48 个数据字符
+const gs1128MaxLength = 48
+
+// GS1 字符集（ISO/IEC 646 子集）中除字母数字外允许的符号
+const gs1Symbols = "!\"%&'()*+,-./:;<=>?_"
+
+// 校验一个 AI 的数据
+func (e GS1Element) validate() error {
+	f, ok := lookupGS1AI(e.AI)
+	if !ok {
+		return fmt.Errorf("不支持的应用标识符 (%s)", e.AI)
+	}
+	if len(e.Value) < f.min || len(e.Value) > f.max {
+		if f.min == f.max {
+			return fmt.Errorf("AI (%s) 数据必须是%d位，当前%d位", e.AI, f.min, len(e.Value))
+		}
+		return fmt.Errorf("AI (%s) 数据长度必须是%d-%d位，当前%d位", e.AI, f.min, f.max, len(e.Value))
+	}
+	for i := 0; i < len(e.Value); i++ {
+		c := e.Value[i]
+		digit := c >= '0' && c <= '9'
+		if f.numeric && !digit {
+			return fmt.Errorf("AI (%s) 数据第%d个字符 %q 无效，只能是数字", e.AI, i+1, c)
+		}
+		letter := (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+		if !digit && !letter && !strings.ContainsRune(gs1Symbols, rune(c)) {
+			return fmt.Errorf("AI (%s) 数据第%d个字符 %q 不在GS1字符集中", e.AI, i+1, c)
+		}
+	}
+	if f.check {
+		n := len(e.Value) - 1
+		want := EANCheckDigit(e.Value[:n])
+		if int(e.Value[n]-'0') != want {
+			return fmt.Errorf("AI (%s) 校验码错误：第%d位应为 %d", e.AI, n+1, want)
+		}
+	}
+	if f.date && !validGS1Date(e.Value) {
+		return fmt.Errorf("AI (%s) 日期无效: %s（格式 YYMMDD，日可以为00）", e.AI, e.Value)
+	}
+	return nil
+}
+
+// 校验 YYMMDD 日期，DD 为 00 表示当月最后一天
+func validGS1Date(s string) bool {
+	year := int(s[0]-'0')*10 + int(s[1]-'0')
+	month := int(s[2]-'0')*10 + int(s[3]-'0')
+	day := int(s[4]-'0')*10 + int(s[5]-'0')
+	if month < 1 || month > 12 {
+		return false
+	}
+	days := [13]int{0, 31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}[month]
+	if month == 2 && year%4 == 0 {
+		days = 29
+	}
+	return day <= days
+}
+
+// ParseGS1 解析 (AI)数据 格式的 GS1-128 字符串并校验，如 (01)09501101530003(17)250101
+func ParseGS1(data string) ([]GS1Element, error) {
+	elements, err := ParseGS1Elements(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGS1128Length(elements); err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+// ParseGS1Elements 解析并校验 (AI)数据 格式的 GS1 字符串，不限制总长度（用于 GS1 Data Matrix）
+// 数据中的 ( 只有后跟 2-4 位数字和 ) 时才开始新的 AI
+func ParseGS1Elements(data string) ([]GS1Element, error) {
+	if !strings.HasPrefix(data, "(") {
+		return nil, fmt.Errorf("GS1数据必须以 (AI) 开头，如 (01)09501101530003")
+	}
+
+	var elements []GS1Element
+	for pos := 0; pos < len(data); {
+		end := strings.IndexByte(data[pos:], ')')
+		if end < 0 {
+			return nil, fmt.Errorf("GS1数据第%d个字符处的AI缺少右括号", pos+1)
+		}
+		ai := data[pos+1 : pos+end]
+		if len(ai) < 2 || len(ai) > 4 || !allDigits(ai) {
+			return nil, fmt.Errorf("GS1数据第%d个字符处的AI无效: (%s)", pos+1, ai)
+		}
+
+		// 数据到下一个 (AI) 为止
+		start := pos + end + 1
+		next := start
+		for next < len(data) && !isGS1AIStart(data[next:]) {
+			next++
+		}
+		elements = append(elements, GS1Element{AI: ai, Value: data[start:next]})
+		pos = next
+	}
+
+	if err := validateGS1(elements); err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+// 是否以 (2-4位数字) 开头
+func isGS1AIStart(s string) bool {
+	end := strings.IndexByte(s, ')')
+	return s[0] == '(' && end >= 3 && end <= 5 && allDigits(s[1:end])
+}
+
+// GS1FromMap 由 AI 映射构造 GS1 数据并校验
+// 预定义长度的 AI 排在前面，以减少 FNC1 分隔符
+func GS1FromMap(m map[string]string) ([]GS1Element, error) {
+	var elements []GS1Element
+	for ai, value := range m {
+		elements = append(elements, GS1Element{AI: ai, Value: value})
+	}
+	sort.Slice(elements, func(i, j int) bool {
+		si, sj := gs1NeedsSeparator(elements[i].AI), gs1NeedsSeparator(elements[j].AI)
+		if si != sj {
+			return !si
+		}
+		return elements[i].AI < elements[j].AI
+	})
+
+	if err := validateGS1(elements); err != nil {
+		return nil, err
+	}
+	if err := checkGS1128Length(elements); err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+func validateGS1(elements []GS1Element) error {
+	if len(elements) == 0 {
+		return fmt.Errorf("GS1数据不能为空")
+	}
+	for _, e := range elements {
+		if err := e.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 检查 GS1-128 的数据字符数（含 FNC1 分隔符）
+func checkGS1128Length(elements []GS1Element) error {
+	length := 0
+	for i, e := range elements {
+		length += len(e.AI) + len(e.Value)
+		if i < len(elements)-1 && gs1NeedsSeparator(e.AI) {
+			length++
+		}
+	}
+	if length > gs1128MaxLength {
+		return fmt.Errorf("GS1-128数据过长（%d个字符，最多%d个）", length, gs1128MaxLength)
+	}
+	return nil
+}
+
+// GS1Text 返回带括号的人眼可读文字
+func GS1Text(elements []GS1Element) string {
+	var sb strings.Builder
+	for _, e := range elements {
+		sb.WriteString("(" + e.AI + ")" + e.Value)
+	}
+	return sb.String()
+}
+
+// EncodeGS1128 编码 GS1-128：以 FNC1 开头，可变长度 AI 之后插入 FNC1 分隔符
+func EncodeGS1128(elements []GS1Element) *Code128 {
+	chars := []int{-1}
+	for i, e := range elements {
+		for _, s := range []string{e.AI, e.Value} {
+			for j := 0; j < len(s); j++ {
+				chars = append(chars, int(s[j]))
+			}
+		}
+		if i < len(elements)-1 && gs1NeedsSeparator(e.AI) {
+			chars = append(chars, -1)
+		}
+	}
+	return planCode128(chars)
+}
@@ -0,0 +1,86 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 去掉 ISBN/ISSN 中的连字符和空格
+func stripSeparators(s string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(s)
+}
+
+// NormalizeISBN 将 ISBN 转换为 13 位 EAN
+// 接受 ISBN-10（自动转换为 978 开头）或以 978/979 开头的 12/13 位数字
+func NormalizeISBN(isbn string) (string, error) {
+	s := strings.ToUpper(stripSeparators(isbn))
+
+	if len(s) == 10 {
+		if !allDigits(s[:9]) || (s[9] != 'X' && (s[9] < '0' || s[9] > '9')) {
+			return "", fmt.Errorf("ISBN-10必须是9位数字加1位校验码（数字或X）")
+		}
+		sum := 0
+		for i := 0; i < 9; i++ {
+			sum += int(s[i]-'0') * (10 - i)
+		}
+		want := (11 - sum%11) % 11
+		if got := checkCharValue(s[9]); got != want {
+			return "", fmt.Errorf("ISBN-10校验码错误：第10位应为 %c", checkChar(want))
+		}
+		s = "978" + s[:9]
+		return s + fmt.Sprintf("%d", EANCheckDigit(s)), nil
+	}
+
+	if (len(s) != 12 && len(s) != 13) || !allDigits(s) {
+		return "", fmt.Errorf("ISBN必须是10位或13位")
+	}
+	if !strings.HasPrefix(s, "978") && !strings.HasPrefix(s, "979") {
+		return "", fmt.Errorf("ISBN必须以978或979开头")
+	}
+	return eanComplete("ISBN", s, 13)
+}
+
+// NormalizeISSN 将 ISSN 转换为 13 位 EAN
+// 接受 8 位 ISSN（自动转换为 977 开头，变体码为 00）或以 977 开头的 12/13 位数字
+func NormalizeISSN(issn string) (string, error) {
+	s := strings.ToUpper(stripSeparators(issn))
+
+	if len(s) == 8 {
+		if !allDigits(s[:7]) || (s[7] != 'X' && (s[7] < '0' || s[7] > '9')) {
+			return "", fmt.Errorf("ISSN必须是7位数字加1位校验码（数字或X）")
+		}
+		sum := 0
+		for i := 0; i < 7; i++ {
+			sum += int(s[i]-'0') * (8 - i)
+		}
+		want := (11 - sum%11) % 11
+		if got := checkCharValue(s[7]); got != want {
+			return "", fmt.Errorf("ISSN校验码错误：第8位应为 %c", checkChar(want))
+		}
+		s = "977" + s[:7] + "00"
+		return s + fmt.Sprintf("%d", EANCheckDigit(s)), nil
+	}
+
+	if (len(s) != 12 && len(s) != 13) || !allDigits(s) {
+		return "", fmt.Errorf("ISSN必须是8位或13位")
+	}
+	if !strings.HasPrefix(s, "977") {
+		return "", fmt.Errorf("ISSN条码必须以977开头")
+	}
+	return eanComplete("ISSN", s, 13)
+}
+
+// 模 11 校验码字符的值，X 表示 10
+func checkCharValue(c byte) int {
+	if c == 'X' {
+		return 10
+	}
+	return int(c - '0')
+}
+
+func checkChar(v int) byte {
+	if v == 10 {
+		return 'X'
+	}
+	return byte('0' + v)
+}
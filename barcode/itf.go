@@ -0,0 +1,57 @@
+package barcode
+
+import (
+	"fmt"
+)
+
+// ITF 数字的宽窄序列（1 为宽）
+var itfPatterns = [10]string{"00110", "10001", "01001", "11000", "00101", "10100", "01100", "00011", "10010", "01010"}
+
+// ITF 宽窄比
+const itfWide = 3
+
+// NormalizeITF14 将 13 位数字补全 GTIN-14 校验位，14 位时校验第 14 位
+func NormalizeITF14(code string) (string, error) {
+	if err := checkDigits("ITF-14", code); err != nil {
+		return "", err
+	}
+	if len(code) != 13 && len(code) != 14 {
+		return "", fmt.Errorf("ITF-14条形码必须是13或14位数字")
+	}
+	return eanComplete("ITF-14", code, 14)
+}
+
+// 编码交叉 25 码（ITF），数据必须为偶数位数字
+func encodeITF(data string) ([]bool, error) {
+	if data == "" {
+		return nil, fmt.Errorf("ITF条形码数据不能为空")
+	}
+	if err := checkDigits("ITF", data); err != nil {
+		return nil, err
+	}
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("ITF条形码必须是偶数位数字（当前%d位），可在前面补0", len(data))
+	}
+
+	modules := appendWidths(nil, "1111") // 起始符：窄条窄空窄条窄空
+	for i := 0; i < len(data); i += 2 {
+		bars := itfPatterns[data[i]-'0']
+		spaces := itfPatterns[data[i+1]-'0']
+		for j := 0; j < 5; j++ {
+			modules = appendNarrowWide(modules, bars[j] == '1', true)
+			modules = appendNarrowWide(modules, spaces[j] == '1', false)
+		}
+	}
+	modules = appendNarrowWide(modules, true, true) // 终止符：宽条窄空窄条
+	modules = appendNarrowWide(modules, false, false)
+	modules = appendNarrowWide(modules, false, true)
+	return modules, nil
+}
+
+// 追加一个宽或窄的条/空
+func appendNarrowWide(modules []bool, wide, bar bool) []bool {
+	if wide {
+		return appendRun(modules, itfWide, bar)
+	}
+	return appendRun(modules, 1, bar)
+}
@@ -0,0 +1,430 @@
+package barcode
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// PDF417 符号限制
+const (
+	pdf417MaxCodewords = 928 // 每个符号最多的码字数（含长度描述符和纠错码字）
+	pdf417MinRows      = 3
+	pdf417MaxRows      = 90
+	pdf417MaxColumns   = 30
+)
+
+// 模式切换码字，数据开头默认为文本压缩
+const (
+	pdf417TextLatch    = 900 // 切换到文本压缩，也用作填充码字
+	pdf417ByteLatch    = 901 // 切换到字节压缩（字节数不是 6 的倍数）
+	pdf417NumericLatch = 902 // 切换到数字压缩
+	pdf417ByteShift    = 913 // 文本压缩中临时编码 1 个字节
+	pdf417ByteLatch6   = 924 // 切换到字节压缩（字节数是 6 的倍数）
+)
+
+// 起始符 17 个模块，终止符 18 个模块
+const (
+	pdf417Start = 0x1fea8
+	pdf417Stop  = 0x3fa29
+)
+
+// 文本压缩子模式
+const (
+	pdf417Alpha = iota
+	pdf417Lower
+	pdf417Mixed
+	pdf417Punct
+)
+
+// 各子模式的字符，下标为字符值；空格在大写、小写和混合子模式中均为 26
+var pdf417TextChars = [4]string{
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"abcdefghijklmnopqrstuvwxyz",
+	"0123456789&\r\t,:#-.$/+%*=^",
+	";<>@[\\]_`~!\r\t,:\n-.$/\"|*()?{}'",
+}
+
+// 子模式切换值
+const (
+	pdf417LL = 27 // 大写、混合切换到小写
+	pdf417AS = 27 // 小写临时切换到大写
+	pdf417ML = 28 // 大写、小写切换到混合
+	pdf417AL = 28 // 混合切换到大写（标点切换到大写为 29）
+	pdf417PL = 25 // 混合切换到标点
+	pdf417PS = 29 // 临时切换到标点，也用于补齐奇数个值
+)
+
+// 字符在子模式中的值，不在该子模式中返回 -1
+func pdf417TextValue(sub int, c byte) int {
+	if c == ' ' && sub != pdf417Punct {
+		return 26
+	}
+	return strings.IndexByte(pdf417TextChars[sub], c)
+}
+
+// 是否可以文本压缩（可打印 ASCII 和 CR、HT、LF）
+func pdf417IsText(c byte) bool {
+	return c == '\t' || c == '\n' || c == '\r' || (c >= ' ' && c <= '~')
+}
+
+func pdf417IsDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// 从开头起连续数字的个数
+func pdf417DigitRun(s string) int {
+	n := 0
+	for n < len(s) && pdf417IsDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+// 从开头起适合文本压缩的字符数，遇到 13 位以上的连续数字时停止
+func pdf417TextRun(s string) int {
+	n := 0
+	for n < len(s) && pdf417IsText(s[n]) {
+		if d := pdf417DigitRun(s[n:]); d >= 13 {
+			break
+		} else if d > 0 {
+			n += d
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+// 从开头起适合字节压缩的字节数，遇到 13 位以上的连续数字或 5 个以上的文本字符时停止
+func pdf417ByteRun(s string) int {
+	n := 0
+	for n < len(s) {
+		if pdf417DigitRun(s[n:]) >= 13 || pdf417TextRun(s[n:]) >= 5 {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// 数据压缩为码字：13 位以上的连续数字用数字压缩，5 个以上的连续文本字符用文本压缩，其余用字节压缩
+func pdf417Compact(data string) []int {
+	var cw []int
+	mode := pdf417TextLatch
+	sub := pdf417Alpha
+	for p := 0; p < len(data); {
+		if n := pdf417DigitRun(data[p:]); n >= 13 {
+			cw = append(cw, pdf417NumericLatch)
+			cw = append(cw, pdf417EncodeNumeric(data[p:p+n])...)
+			mode = pdf417NumericLatch
+			p += n
+			continue
+		}
+		// 已在文本压缩中时，短文本也不切换模式
+		if n := pdf417TextRun(data[p:]); n >= 5 || (n > 0 && mode == pdf417TextLatch) {
+			if mode != pdf417TextLatch {
+				cw = append(cw, pdf417TextLatch)
+				mode = pdf417TextLatch
+				sub = pdf417Alpha
+			}
+			var text []int
+			text, sub = pdf417EncodeText(data[p:p+n], sub)
+			cw = append(cw, text...)
+			p += n
+			continue
+		}
+		n := pdf417ByteRun(data[p:])
+		if n == 1 && mode == pdf417TextLatch {
+			cw = append(cw, pdf417ByteShift, int(data[p]))
+		} else {
+			if n%6 == 0 {
+				cw = append(cw, pdf417ByteLatch6)
+			} else {
+				cw = append(cw, pdf417ByteLatch)
+			}
+			cw = append(cw, pdf417EncodeBytes(data[p:p+n])...)
+			mode = pdf417ByteLatch
+		}
+		p += n
+	}
+	return cw
+}
+
+// 文本压缩：每个码字容纳两个子模式值，返回码字和结束时的子模式
+func pdf417EncodeText(text string, sub int) ([]int, int) {
+	var values []int
+	for i := 0; i < len(text); {
+		c := text[i]
+		if v := pdf417TextValue(sub, c); v >= 0 {
+			values = append(values, v)
+			i++
+			continue
+		}
+		switch sub {
+		case pdf417Alpha:
+			switch {
+			case pdf417TextValue(pdf417Lower, c) >= 0:
+				values = append(values, pdf417LL)
+				sub = pdf417Lower
+			case pdf417TextValue(pdf417Mixed, c) >= 0:
+				values = append(values, pdf417ML)
+				sub = pdf417Mixed
+			default:
+				values = append(values, pdf417PS, pdf417TextValue(pdf417Punct, c))
+				i++
+			}
+		case pdf417Lower:
+			switch {
+			case pdf417TextValue(pdf417Alpha, c) >= 0:
+				values = append(values, pdf417AS, pdf417TextValue(pdf417Alpha, c))
+				i++
+			case pdf417TextValue(pdf417Mixed, c) >= 0:
+				values = append(values, pdf417ML)
+				sub = pdf417Mixed
+			default:
+				values = append(values, pdf417PS, pdf417TextValue(pdf417Punct, c))
+				i++
+			}
+		case pdf417Mixed:
+			switch {
+			case pdf417TextValue(pdf417Alpha, c) >= 0:
+				values = append(values, pdf417AL)
+				sub = pdf417Alpha
+			case pdf417TextValue(pdf417Lower, c) >= 0:
+				values = append(values, pdf417LL)
+				sub = pdf417Lower
+			case i+1 < len(text) && pdf417TextValue(pdf417Punct, text[i+1]) >= 0:
+				// 连续两个以上标点时切换到标点子模式
+				values = append(values, pdf417PL)
+				sub = pdf417Punct
+			default:
+				values = append(values, pdf417PS, pdf417TextValue(pdf417Punct, c))
+				i++
+			}
+		default:
+			values = append(values, 29) // 标点切换到大写
+			sub = pdf417Alpha
+		}
+	}
+	if len(values)%2 == 1 {
+		values = append(values, pdf417PS)
+	}
+
+	cw := make([]int, 0, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		cw = append(cw, values[i]*30+values[i+1])
+	}
+	return cw, sub
+}
+
+// 数字压缩：每组最多 44 位数字，前置 1 后转换为 900 进制
+func pdf417EncodeNumeric(digits string) []int {
+	var cw []int
+	base := big.NewInt(900)
+	for len(digits) > 0 {
+		n := min(len(digits), 44)
+		v, _ := new(big.Int).SetString("1"+digits[:n], 10)
+		var group []int
+		mod := new(big.Int)
+		for v.Sign() > 0 {
+			v.DivMod(v, base, mod)
+			group = append([]int{int(mod.Int64())}, group...)
+		}
+		cw = append(cw, group...)
+		digits = digits[n:]
+	}
+	return cw
+}
+
+// 字节压缩：每 6 字节转换为 5 个 900 进制码字，不足 6 字节的部分每字节一个码字
+func pdf417EncodeBytes(data string) []int {
+	var cw []int
+	for ; len(data) >= 6; data = data[6:] {
+		var v uint64
+		for i := 0; i < 6; i++ {
+			v = v<<8 | uint64(data[i])
+		}
+		var group [5]int
+		for i := 4; i >= 0; i-- {
+			group[i] = int(v % 900)
+			v /= 900
+		}
+		cw = append(cw, group[:]...)
+	}
+	for i := 0; i < len(data); i++ {
+		cw = append(cw, int(data[i]))
+	}
+	return cw
+}
+
+// 纠错码字：GF(929) 上的 Reed-Solomon，生成多项式的根为 3^1 ... 3^k，k = 2^(level+1)
+func pdf417ECC(data []int, level int) []int {
+	k := 2 << uint(level)
+
+	// 生成多项式，g[i] 为 x^i 的系数
+	g := make([]int, k+1)
+	g[0] = 1
+	root := 1
+	for i := 1; i <= k; i++ {
+		root = root * 3 % 929
+		for j := i; j > 0; j-- {
+			g[j] = (g[j-1] + 929 - root*g[j]%929) % 929
+		}
+		g[0] = (929 - root*g[0]%929) % 929
+	}
+
+	// 多项式除法余数，ecc[0] 为最高次项；纠错码字为余数取负
+	ecc := make([]int, k)
+	for _, d := range data {
+		t := (d + ecc[0]) % 929
+		copy(ecc, ecc[1:])
+		ecc[k-1] = 0
+		for j := range ecc {
+			ecc[j] = (ecc[j] + 929 - t*g[k-1-j]%929) % 929
+		}
+	}
+	for j := range ecc {
+		if ecc[j] != 0 {
+			ecc[j] = 929 - ecc[j]
+		}
+	}
+	return ecc
+}
+
+// PDF417AutoLevel 按数据码字数推荐纠错等级
+func PDF417AutoLevel(data string) int {
+	switch n := len(pdf417Compact(data)); {
+	case n <= 40:
+		return 2
+	case n <= 160:
+		return 3
+	case n <= 320:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// PDF417Codewords 返回数据在纠错等级 level 下需要的码字数（长度描述符 + 数据 + 纠错码字）
+func PDF417Codewords(data string, level int) int {
+	return 1 + len(pdf417Compact(data)) + 2<<uint(level)
+}
+
+// PDF417Capacity 返回指定列数和行数（0 为自动）下符号最多容纳的码字数
+func PDF417Capacity(columns, rows int) int {
+	capacity := pdf417MaxCodewords
+	switch {
+	case columns > 0 && rows > 0:
+		capacity = columns * rows
+	case columns > 0:
+		capacity = columns * pdf417MaxRows
+	case rows > 0:
+		capacity = rows * pdf417MaxColumns
+	}
+	return min(capacity, pdf417MaxCodewords)
+}
+
+// 确定行列数：未指定的一项按码字数 n 计算，都未指定时使行数不超过列数的 3 倍
+func pdf417Layout(n, columns, rows int) (int, int) {
+	switch {
+	case columns > 0 && rows > 0:
+	case columns > 0:
+		rows = (n + columns - 1) / columns
+	case rows > 0:
+		columns = (n + rows - 1) / rows
+	default:
+		columns = 1
+		for (n+columns-1)/columns > 3*columns {
+			columns++
+		}
+		rows = (n + columns - 1) / columns
+	}
+	return columns, max(rows, pdf417MinRows)
+}
+
+// 编码为符号码字（长度描述符、数据、填充、纠错码字），返回码字和行列数
+func pdf417Symbol(data string, columns, rows, level int) ([]int, int, int, error) {
+	if data == "" {
+		return nil, 0, 0, fmt.Errorf("PDF417数据不能为空")
+	}
+	if columns < 0 || columns > pdf417MaxColumns {
+		return nil, 0, 0, fmt.Errorf("PDF417列数必须是 1-30（0为自动）: %d", columns)
+	}
+	if rows != 0 && (rows < pdf417MinRows || rows > pdf417MaxRows) {
+		return nil, 0, 0, fmt.Errorf("PDF417行数必须是 3-90（0为自动）: %d", rows)
+	}
+	if level < 0 || level > 8 {
+		return nil, 0, 0, fmt.Errorf("PDF417纠错等级必须是 0-8: %d", level)
+	}
+
+	compacted := pdf417Compact(data)
+	eccCount := 2 << uint(level)
+	required := 1 + len(compacted) + eccCount
+	if capacity := PDF417Capacity(columns, rows); required > capacity {
+		return nil, 0, 0, fmt.Errorf("PDF417数据过长：需要%d个码字，当前设置最多%d个", required, capacity)
+	}
+	columns, rows = pdf417Layout(required, columns, rows)
+
+	// 长度描述符计入数据和填充码字，不含纠错码字
+	length := columns*rows - eccCount
+	codewords := make([]int, 0, columns*rows)
+	codewords = append(codewords, length)
+	codewords = append(codewords, compacted...)
+	for len(codewords) < length {
+		codewords = append(codewords, pdf417TextLatch)
+	}
+	codewords = append(codewords, pdf417ECC(codewords, level)...)
+	return codewords, columns, rows, nil
+}
+
+// 行指示符：左右各一个，记录行数、列数和纠错等级，按簇轮换
+func pdf417RowIndicators(row, rows, columns, level int) (left, right int) {
+	base := row / 3 * 30
+	rowInfo := (rows - 1) / 3
+	levelInfo := level*3 + (rows-1)%3
+	switch row % 3 {
+	case 0:
+		return base + rowInfo, base + columns - 1
+	case 1:
+		return base + levelInfo, base + rowInfo
+	default:
+		return base + columns - 1, base + levelInfo
+	}
+}
+
+// EncodePDF417 编码 PDF417，columns、rows 为数据列数和行数（0 为自动），level 为纠错等级 0-8，
+// 返回每个模块一个像素、每行 rowHeight 个像素、不含静区的位图
+func EncodePDF417(data string, columns, rows, level, rowHeight int) (*Bitmap, error) {
+	if rowHeight < 1 {
+		return nil, fmt.Errorf("PDF417行高必须大于 0: %d", rowHeight)
+	}
+	codewords, columns, rows, err := pdf417Symbol(data, columns, rows, level)
+	if err != nil {
+		return nil, err
+	}
+
+	bm := NewBitmap(17*(columns+4)+1, rows*rowHeight)
+	for row := 0; row < rows; row++ {
+		x := 0
+		draw := func(pattern, width int) {
+			for i := width - 1; i >= 0; i-- {
+				if pattern>>uint(i)&1 == 1 {
+					bm.FillRect(x, row*rowHeight, 1, rowHeight, true)
+				}
+				x++
+			}
+		}
+
+		cluster := row % 3
+		left, right := pdf417RowIndicators(row, rows, columns, level)
+		draw(pdf417Start, 17)
+		draw(pdf417Patterns[cluster][left], 17)
+		for _, cw := range codewords[row*columns : (row+1)*columns] {
+			draw(pdf417Patterns[cluster][cw], 17)
+		}
+		draw(pdf417Patterns[cluster][right], 17)
+		draw(pdf417Stop, 18)
+	}
+	return bm, nil
+}
@@ -0,0 +1,212 @@
+package barcode
+
+import (
+	"strings"
+	"testing"
+)
+
+// 码字图形：17 个模块、4 条 4 空、单元宽度 1-6，簇号 (b1-b2+b3-b4) mod 9 为 0、3、6
+func TestPDF417Patterns(t *testing.T) {
+	for cluster, patterns := range pdf417Patterns {
+		seen := map[int]bool{}
+		for value, pattern := range patterns {
+			var modules []bool
+			for i := 16; i >= 0; i-- {
+				modules = append(modules, pattern>>uint(i)&1 == 1)
+			}
+			widths := moduleWidths(modules)
+			if pattern>>17 != 0 || !modules[0] || modules[16] || len(widths) != 8 || strings.ContainsAny(widths, "789") {
+				t.Fatalf("簇 %d 码字 %d 的图形 %s 无效", cluster, value, widths)
+			}
+			k := (int(widths[0]) - int(widths[2]) + int(widths[4]) - int(widths[6]) + 9) % 9
+			if k != cluster*3 {
+				t.Fatalf("簇 %d 码字 %d 的图形 %s 属于簇 %d", cluster, value, widths, k)
+			}
+			if seen[pattern] {
+				t.Fatalf("簇 %d 码字 %d 的图形重复", cluster, value)
+			}
+			seen[pattern] = true
+		}
+	}
+}
+
+// 规范示例：数据码字 5 453 178 121 239，纠错等级 1
+func TestPDF417ECC(t *testing.T) {
+	got := pdf417ECC([]int{5, 453, 178, 121, 239}, 1)
+	want := []int{452, 327, 657, 619}
+	for i := range want {
+		if len(got) != len(want) || got[i] != want[i] {
+			t.Fatalf("纠错码字为 %v，应为 %v", got, want)
+		}
+	}
+}
+
+func TestPDF417Compact(t *testing.T) {
+	cases := []struct {
+		data string
+		want []int
+	}{
+		{"PDF417", []int{453, 178, 121, 239}},                       // 规范示例：文本压缩
+		{"000213298174000", []int{902, 1, 624, 434, 632, 282, 200}}, // 规范示例：数字压缩
+		{"\x00\x01\x02\x03\x04\x05", []int{924, 0, 5, 844, 88, 165}},
+		{"\x00\x01\x02\x03\x04\x05\x06", []int{901, 0, 5, 844, 88, 165, 6}},
+		{"AB", []int{1}},
+		{"Ab", []int{27, 59}},              // A ll b ps
+		{"a;<b", []int{810, 870, 871, 59}}, // 小写中的标点使用 ps
+		{"1", []int{841}},                  // 短数字使用文本压缩的混合子模式
+		{"A\x80", []int{29, 913, 128}},
+		{"ABCDE\x80\x81", []int{1, 63, 149, 901, 128, 129}},
+	}
+	for _, tc := range cases {
+		got := pdf417Compact(tc.data)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%q 压缩为 %v，应为 %v", tc.data, got, tc.want)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Fatalf("%q 压缩为 %v，应为 %v", tc.data, got, tc.want)
+			}
+		}
+	}
+
+	counts := []struct {
+		data string
+		want int
+	}{
+		{strings.Repeat("9", 44), 16},
+		{strings.Repeat("9", 47), 18},
+		{strings.Repeat("9", 88), 31},
+		{strings.Repeat("x", 100), 51},
+	}
+	for _, tc := range counts {
+		if got := len(pdf417Compact(tc.data)); got != tc.want {
+			t.Errorf("%d 个 %q 压缩为 %d 个码字，应为 %d 个", len(tc.data), tc.data[0], got, tc.want)
+		}
+	}
+}
+
+// 从位图读回每行的码字，检查行指示符、长度描述符和纠错码字
+func TestEncodePDF417Decode(t *testing.T) {
+	reverse := [3]map[int]int{{}, {}, {}}
+	for cluster, patterns := range pdf417Patterns {
+		for value, pattern := range patterns {
+			reverse[cluster][pattern] = value
+		}
+	}
+	read := func(bm *Bitmap, x, y, width int) int {
+		v := 0
+		for i := 0; i < width; i++ {
+			v <<= 1
+			if bm.Get(x+i, y) {
+				v |= 1
+			}
+		}
+		return v
+	}
+
+	cases := []struct {
+		data                  string
+		columns, rows, level  int
+		wantColumns, wantRows int
+	}{
+		{"Hello, PDF417! 1234567890123", 3, 0, 2, 3, 9},
+		{"PDF417", 0, 0, 0, 2, 4},
+		{"\x00\x01\x02 binary \xff", 0, 5, 3, 6, 5},
+		{strings.Repeat("0123456789", 20), 4, 40, 5, 4, 40},
+	}
+	for _, tc := range cases {
+		const rowHeight = 2
+		bm, err := EncodePDF417(tc.data, tc.columns, tc.rows, tc.level, rowHeight)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.data, err)
+		}
+		columns := (bm.Width-1)/17 - 4
+		rows := bm.Height / rowHeight
+		if columns != tc.wantColumns || rows != tc.wantRows || bm.Width != 17*(columns+4)+1 {
+			t.Fatalf("%q 为 %d 列 %d 行（宽 %d），应为 %d 列 %d 行", tc.data, columns, rows, bm.Width, tc.wantColumns, tc.wantRows)
+		}
+
+		var codewords []int
+		for row := 0; row < rows; row++ {
+			y := row * rowHeight
+			for x := 0; x < bm.Width; x++ {
+				if bm.Get(x, y) != bm.Get(x, y+1) {
+					t.Fatalf("%q 第 %d 行高度不一致", tc.data, row)
+				}
+			}
+			if read(bm, 0, y, 17) != pdf417Start || read(bm, bm.Width-18, y, 18) != pdf417Stop {
+				t.Fatalf("%q 第 %d 行起始符或终止符错误", tc.data, row)
+			}
+			var values []int
+			for col := 0; col < columns+2; col++ {
+				v, ok := reverse[row%3][read(bm, 17+17*col, y, 17)]
+				if !ok {
+					t.Fatalf("%q 第 %d 行第 %d 个码字不属于簇 %d", tc.data, row, col, row%3*3)
+				}
+				values = append(values, v)
+			}
+
+			// 行指示符：簇 0 左侧为行数，簇 1 左侧为纠错等级，簇 2 左侧为列数，右侧依次轮换
+			left, right := values[0]%30, values[columns+1]%30
+			rowInfo, levelInfo, columnInfo := (rows-1)/3, tc.level*3+(rows-1)%3, columns-1
+			want := [3][2]int{{rowInfo, columnInfo}, {levelInfo, rowInfo}, {columnInfo, levelInfo}}[row%3]
+			if values[0]/30 != row/3 || values[columns+1]/30 != row/3 || left != want[0] || right != want[1] {
+				t.Fatalf("%q 第 %d 行的行指示符为 %d、%d", tc.data, row, values[0], values[columns+1])
+			}
+			codewords = append(codewords, values[1:columns+1]...)
+		}
+
+		// 纠错码字使码字多项式在 3^1 ... 3^k 处为 0
+		k := 2 << uint(tc.level)
+		root := 1
+		for j := 1; j <= k; j++ {
+			root = root * 3 % 929
+			s := 0
+			for _, c := range codewords {
+				s = (s*root + c) % 929
+			}
+			if s != 0 {
+				t.Fatalf("%q 的第 %d 个校验子为 %d", tc.data, j, s)
+			}
+		}
+
+		length := codewords[0]
+		if length != len(codewords)-k {
+			t.Fatalf("%q 的长度描述符为 %d，应为 %d", tc.data, length, len(codewords)-k)
+		}
+		data := pdf417Compact(tc.data)
+		for i, c := range codewords[1:length] {
+			if i < len(data) && c != data[i] || i >= len(data) && c != pdf417TextLatch {
+				t.Fatalf("%q 的数据码字为 %v，应为 %v 加填充", tc.data, codewords[1:length], data)
+			}
+		}
+	}
+}
+
+func TestEncodePDF417Errors(t *testing.T) {
+	cases := []struct {
+		data                 string
+		columns, rows, level int
+	}{
+		{"", 0, 0, 2},
+		{strings.Repeat("x", 100), 1, 3, 2},
+		{"x", 31, 0, 2},
+		{"x", 0, 2, 2},
+		{"x", 0, 0, 9},
+		{strings.Repeat("\xff", 1200), 0, 0, 2},
+	}
+	for _, tc := range cases {
+		if _, err := EncodePDF417(tc.data, tc.columns, tc.rows, tc.level, 3); err == nil {
+			t.Errorf("%d 字节、%d 列 %d 行、等级 %d 应返回错误", len(tc.data), tc.columns, tc.rows, tc.level)
+		}
+	}
+
+	// 容量边界与 PDF417Codewords 一致：1 列 90 行
+	for n := 1; n < 200; n++ {
+		data := strings.Repeat("\xff", n)
+		_, err := EncodePDF417(data, 1, 0, 2, 3)
+		if fits := PDF417Codewords(data, 2) <= PDF417Capacity(1, 0); fits != (err == nil) {
+			t.Fatalf("%d 字节需要 %d 个码字，编码结果 %v", n, PDF417Codewords(data, 2), err)
+		}
+	}
+}
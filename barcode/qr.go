@@ -0,0 +1,538 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QR 码纠错等级
+const (
+	QRLevelL = iota
+	QRLevelM
+	QRLevelQ
+	QRLevelH
+)
+
+// ParseQRLevel 解析纠错等级字符串 L/M/Q/H，空字符串为 M
+func ParseQRLevel(level string) (int, error) {
+	switch strings.ToUpper(level) {
+	case "L":
+		return QRLevelL, nil
+	case "", "M":
+		return QRLevelM, nil
+	case "Q":
+		return QRLevelQ, nil
+	case "H":
+		return QRLevelH, nil
+	default:
+		return 0, fmt.Errorf("QR码纠错等级必须是 L、M、Q 或 H: %s", level)
+	}
+}
+
+// 每块纠错码字数，下标为 [等级][版本]
+var qrECCPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// 纠错块数，下标为 [等级][版本]
+var qrBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// 格式信息中的纠错等级编码
+var qrLevelBits = [4]int{1, 0, 3, 2}
+
+// QR 编码模式
+const (
+	qrModeNumeric      = 1
+	qrModeAlphanumeric = 2
+	qrModeByte         = 4
+)
+
+const qrAlphanumericChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// 版本的原始数据模块数（去除功能图形后）
+func qrRawModules(ver int) int {
+	result := (16*ver+128)*ver + 64
+	if ver >= 2 {
+		numAlign := ver/7 + 2
+		result -= (25*numAlign-10)*numAlign - 55
+		if ver >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+// 版本在指定纠错等级下的数据码字数
+func qrDataCodewords(ver, level int) int {
+	return qrRawModules(ver)/8 - qrECCPerBlock[level][ver]*qrBlocks[level][ver]
+}
+
+// 字符计数指示符的位数
+func qrCountBits(mode, ver int) int {
+	idx := 0
+	if ver >= 27 {
+		idx = 2
+	} else if ver >= 10 {
+		idx = 1
+	}
+	switch mode {
+	case qrModeNumeric:
+		return [3]int{10, 12, 14}[idx]
+	case qrModeAlphanumeric:
+		return [3]int{9, 11, 13}[idx]
+	default:
+		return [3]int{8, 16, 16}[idx]
+	}
+}
+
+// 位流
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>uint(i))&1 == 1)
+	}
+}
+
+// 选择能容纳全部数据的最紧凑模式
+func qrChooseMode(data string) int {
+	numeric, alnum := true, true
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c < '0' || c > '9' {
+			numeric = false
+		}
+		if strings.IndexByte(qrAlphanumericChars, c) < 0 {
+			alnum = false
+		}
+	}
+	switch {
+	case numeric:
+		return qrModeNumeric
+	case alnum:
+		return qrModeAlphanumeric
+	default:
+		return qrModeByte
+	}
+}
+
+// 按模式编码数据段（不含模式和计数指示符）
+func qrEncodeData(data string, mode int) bitBuffer {
+	var bb bitBuffer
+	switch mode {
+	case qrModeNumeric:
+		for i := 0; i < len(data); i += 3 {
+			chunk := data[i:min(i+3, len(data))]
+			n := 0
+			for j := 0; j < len(chunk); j++ {
+				n = n*10 + int(chunk[j]-'0')
+			}
+			bb.append(n, len(chunk)*3+1)
+		}
+	case qrModeAlphanumeric:
+		for i := 0; i < len(data); i += 2 {
+			v := strings.IndexByte(qrAlphanumericChars, data[i])
+			if i+1 < len(data) {
+				bb.append(v*45+strings.IndexByte(qrAlphanumericChars, data[i+1]), 11)
+			} else {
+				bb.append(v, 6)
+			}
+		}
+	default:
+		for i := 0; i < len(data); i++ {
+			bb.append(int(data[i]), 8)
+		}
+	}
+	return bb
+}
+
+// QRCapacity 返回最大版本下可容纳的字节数，用于错误提示
+func QRCapacity(level int) int {
+	return (qrDataCodewords(40, level)*8 - 4 - qrCountBits(qrModeByte, 40)) / 8
+}
+
+// QRCodewords 返回数据在最大版本下需要的数据码字数和该纠错等级可用的数据码字数
+func QRCodewords(data string, level int) (required, capacity int) {
+	mode := qrChooseMode(data)
+	bits := 4 + qrCountBits(mode, 40) + len(qrEncodeData(data, mode))
+	return (bits + 7) / 8, qrDataCodewords(40, level)
+}
+
+// EncodeQR 编码 QR 码（Model 2），返回每个模块一个像素、不含静区的矩阵
+func EncodeQR(data string, level int) (*Bitmap, error) {
+	if data == "" {
+		return nil, fmt.Errorf("QR码数据不能为空")
+	}
+	if level < QRLevelL || level > QRLevelH {
+		return nil, fmt.Errorf("QR码纠错等级无效: %d", level)
+	}
+
+	mode := qrChooseMode(data)
+	payload := qrEncodeData(data, mode)
+
+	// 选择最小可用版本
+	ver := 0
+	for v := 1; v <= 40; v++ {
+		if 4+qrCountBits(mode, v)+len(payload) <= qrDataCodewords(v, level)*8 {
+			ver = v
+			break
+		}
+	}
+	if ver == 0 {
+		return nil, fmt.Errorf("QR码数据过长（%d字节），当前纠错等级最多约%d字节", len(data), QRCapacity(level))
+	}
+
+	// 组装位流：模式 + 计数 + 数据 + 终止符 + 填充
+	capacity := qrDataCodewords(ver, level) * 8
+	var bb bitBuffer
+	bb.append(mode, 4)
+	bb.append(len(data), qrCountBits(mode, ver))
+	bb = append(bb, payload...)
+	bb.append(0, min(4, capacity-len(bb)))
+	bb.append(0, (8-len(bb)%8)%8)
+	for pad := 0xEC; len(bb) < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	codewords := make([]int, len(bb)/8)
+	for i, bit := range bb {
+		if bit {
+			codewords[i>>3] |= 0x80 >> uint(i&7)
+		}
+	}
+
+	q := newQRMatrix(ver)
+	q.drawFunctionPatterns()
+	q.drawCodewords(qrAddECC(codewords, ver, level))
+	q.applyBestMask(level)
+	return q.bitmap(), nil
+}
+
+// 分块计算纠错码字并交错排列
+func qrAddECC(data []int, ver, level int) []int {
+	numBlocks := qrBlocks[level][ver]
+	eccLen := qrECCPerBlock[level][ver]
+	rawCodewords := qrRawModules(ver) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortBlockLen := rawCodewords / numBlocks
+
+	gen := qrField.generator(eccLen, 0)
+	blocks := make([][]int, numBlocks)
+	k := 0
+	for i := 0; i < numBlocks; i++ {
+		n := shortBlockLen - eccLen
+		if i >= numShortBlocks {
+			n++
+		}
+		dat := data[k : k+n]
+		k += n
+		block := append([]int{}, dat...)
+		if i < numShortBlocks {
+			block = append(block, 0) // 占位，使各块等长便于交错
+		}
+		blocks[i] = append(block, qrField.ecc(dat, gen)...)
+	}
+
+	var result []int
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			if i != shortBlockLen-eccLen || j >= numShortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// qrMatrix QR 码模块矩阵
+type qrMatrix struct {
+	ver        int
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+func newQRMatrix(ver int) *qrMatrix {
+	size := ver*4 + 17
+	q := &qrMatrix{ver: ver, size: size}
+	q.modules = make([][]bool, size)
+	q.isFunction = make([][]bool, size)
+	for i := range q.modules {
+		q.modules[i] = make([]bool, size)
+		q.isFunction[i] = make([]bool, size)
+	}
+	return q
+}
+
+func (q *qrMatrix) setFunction(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.isFunction[y][x] = true
+}
+
+// 校正图形中心坐标
+func (q *qrMatrix) alignmentPositions() []int {
+	if q.ver == 1 {
+		return nil
+	}
+	numAlign := q.ver/7 + 2
+	step := (q.ver*8 + numAlign*3 + 5) / (numAlign*4 - 4) * 2
+	result := make([]int, numAlign)
+	result[0] = 6
+	for i, pos := numAlign-1, q.size-7; i >= 1; i, pos = i-1, pos-step {
+		result[i] = pos
+	}
+	return result
+}
+
+// 绘制定位、定时、校正图形并预留格式和版本信息区域
+func (q *qrMatrix) drawFunctionPatterns() {
+	for i := 0; i < q.size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+
+	q.drawFinder(3, 3)
+	q.drawFinder(q.size-4, 3)
+	q.drawFinder(3, q.size-4)
+
+	pos := q.alignmentPositions()
+	n := len(pos)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if (i == 0 && j == 0) || (i == 0 && j == n-1) || (i == n-1 && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.setFunction(pos[i]+dx, pos[j]+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	q.drawFormatBits(0, 0)
+	q.drawVersion()
+}
+
+func (q *qrMatrix) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx >= 0 && xx < q.size && yy >= 0 && yy < q.size {
+				dist := max(abs(dx), abs(dy))
+				q.setFunction(xx, yy, dist != 2 && dist != 4)
+			}
+		}
+	}
+}
+
+// 绘制格式信息（两份）
+func (q *qrMatrix) drawFormatBits(level, mask int) {
+	data := qrLevelBits[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.setFunction(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.size-15+i, bit(i))
+	}
+	q.setFunction(8, q.size-8, true) // 固定暗模块
+}
+
+// 绘制版本信息（版本 7 及以上）
+func (q *qrMatrix) drawVersion() {
+	if q.ver < 7 {
+		return
+	}
+	rem := q.ver
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := q.ver<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>uint(i))&1 == 1
+		a, b := q.size-11+i%3, i/3
+		q.setFunction(a, b, dark)
+		q.setFunction(b, a, dark)
+	}
+}
+
+// 按之字形顺序放置数据码字
+func (q *qrMatrix) drawCodewords(data []int) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				upward := (right+1)&2 == 0
+				y := vert
+				if upward {
+					y = q.size - 1 - vert
+				}
+				if !q.isFunction[y][x] && i < len(data)*8 {
+					q.modules[y][x] = (data[i>>3]>>uint(7-i&7))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// 掩模条件
+func qrMaskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+func (q *qrMatrix) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if !q.isFunction[y][x] && qrMaskBit(mask, x, y) {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// 尝试全部掩模，选择罚分最低者
+func (q *qrMatrix) applyBestMask(level int) {
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(level, mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		q.applyMask(mask) // 异或两次即还原
+	}
+	q.applyMask(best)
+	q.drawFormatBits(level, best)
+}
+
+// 计算掩模罚分（连续同色、2x2 色块、类定位图形、明暗比例）
+func (q *qrMatrix) penalty() int {
+	size := q.size
+	get := func(x, y int, horizontal bool) bool {
+		if horizontal {
+			return q.modules[y][x]
+		}
+		return q.modules[x][y]
+	}
+
+	penalty := 0
+	for _, horizontal := range []bool{true, false} {
+		for y := 0; y < size; y++ {
+			run := 1
+			for x := 1; x < size; x++ {
+				if get(x, y, horizontal) == get(x-1, y, horizontal) {
+					run++
+					if run == 5 {
+						penalty += 3
+					} else if run > 5 {
+						penalty++
+					}
+				} else {
+					run = 1
+				}
+			}
+
+			// 1:1:3:1:1 图形，任一侧有 4 个浅色模块
+			for x := 0; x+7 <= size; x++ {
+				pattern := get(x, y, horizontal) && !get(x+1, y, horizontal) && get(x+2, y, horizontal) &&
+					get(x+3, y, horizontal) && get(x+4, y, horizontal) && !get(x+5, y, horizontal) && get(x+6, y, horizontal)
+				if !pattern {
+					continue
+				}
+				lightBefore, lightAfter := true, true
+				for k := 1; k <= 4; k++ {
+					if x-k >= 0 && get(x-k, y, horizontal) {
+						lightBefore = false
+					}
+					if x+6+k < size && get(x+6+k, y, horizontal) {
+						lightAfter = false
+					}
+				}
+				if lightBefore || lightAfter {
+					penalty += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			c := q.modules[y][x]
+			if c {
+				dark++
+			}
+			if x+1 < size && y+1 < size && c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+				penalty += 3
+			}
+		}
+	}
+	total := size * size
+	deviation := abs(dark*20-total*10) / total // 每偏离 5% 计一级
+	penalty += deviation * 10
+	return penalty
+}
+
+func (q *qrMatrix) bitmap() *Bitmap {
+	bm := NewBitmap(q.size, q.size)
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			bm.Set(x, y, q.modules[y][x])
+		}
+	}
+	return bm
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
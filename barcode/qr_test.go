@@ -0,0 +1,195 @@
+package barcode
+
+import (
+	"strings"
+	"testing"
+)
+
+// HELLO WORLD（1-M，字母数字模式）的数据码字和纠错码字
+var helloWorld1M = []int{
+	32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17,
+	196, 35, 39, 119, 235, 215, 231, 226, 93, 23,
+}
+
+func TestQRErrorCorrection(t *testing.T) {
+	data := helloWorld1M[:16]
+	got := qrField.ecc(data, qrField.generator(10, 0))
+	for i, want := range helloWorld1M[16:] {
+		if got[i] != want {
+			t.Fatalf("纠错码字为 %v，应为 %v", got, helloWorld1M[16:])
+		}
+	}
+}
+
+// 版本 1 M 级各掩码的格式信息（bit14..bit0）
+var qrFormatM = [8]string{
+	"101010000010010", "101000100100101", "101111001111100", "101101101001011",
+	"100010111111001", "100000011001110", "100111110010111", "100101010100000",
+}
+
+// 规范定义的掩码条件，true 时翻转模块
+func qrTestMask(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (y/2+x/3)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	}
+	return ((x+y)%2+x*y%3)%2 == 0
+}
+
+// 从矩阵中读回版本 1 QR 码的格式信息和全部码字
+func TestEncodeQRDecode(t *testing.T) {
+	bm, err := EncodeQR("HELLO WORLD", QRLevelM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const size = 21
+	if bm.Width != size || bm.Height != size {
+		t.Fatalf("HELLO WORLD 应为版本 1（21x21），实际 %dx%d", bm.Width, bm.Height)
+	}
+
+	// 三个定位图形的中心 3x3 为深色，外圈 7x7 的边为深色
+	for _, corner := range [][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for i := 0; i < 7; i++ {
+			x, y := corner[0], corner[1]
+			if !bm.Get(x+i, y) || !bm.Get(x+i, y+6) || !bm.Get(x, y+i) || !bm.Get(x+6, y+i) || !bm.Get(x+2+i%3, y+2+i/3) {
+				t.Fatalf("定位图形 (%d,%d) 错误", x, y)
+			}
+		}
+	}
+
+	// 格式信息：bit0-5 在第 8 列上部，随后绕过定时图形
+	var format [15]bool
+	for i := 0; i <= 5; i++ {
+		format[i] = bm.Get(8, i)
+	}
+	format[6], format[7], format[8] = bm.Get(8, 7), bm.Get(8, 8), bm.Get(7, 8)
+	for i := 9; i < 15; i++ {
+		format[i] = bm.Get(14-i, 8)
+	}
+	mask := -1
+	for m, bits := range qrFormatM {
+		match := true
+		for i := 0; i < 15; i++ {
+			if (bits[14-i] == '1') != format[i] {
+				match = false
+			}
+		}
+		if match {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		t.Fatalf("格式信息 %v 不是 M 级的有效格式", format)
+	}
+	// 第二份格式信息与第一份相同
+	for i := 0; i < 8; i++ {
+		if bm.Get(size-1-i, 8) != format[i] {
+			t.Errorf("第二份格式信息第 %d 位与第一份不同", i)
+		}
+	}
+	for i := 8; i < 15; i++ {
+		if bm.Get(8, size-15+i) != format[i] {
+			t.Errorf("第二份格式信息第 %d 位与第一份不同", i)
+		}
+	}
+
+	// 按之字形顺序读取数据区，跳过定位图形、格式信息和定时图形
+	function := func(x, y int) bool {
+		return x == 6 || y == 6 || (x <= 8 && y <= 8) || (x >= size-8 && y <= 8) || (x <= 8 && y >= size-8)
+	}
+	var codewords []int
+	bit := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = size - 1 - vert // 向上
+				}
+				if function(x, y) {
+					continue
+				}
+				if bit%8 == 0 {
+					codewords = append(codewords, 0)
+				}
+				if bm.Get(x, y) != qrTestMask(mask, x, y) {
+					codewords[bit/8] |= 0x80 >> uint(bit%8)
+				}
+				bit++
+			}
+		}
+	}
+	if len(codewords) != len(helloWorld1M) {
+		t.Fatalf("读出 %d 个码字，应为 %d 个", len(codewords), len(helloWorld1M))
+	}
+	for i := range codewords {
+		if codewords[i] != helloWorld1M[i] {
+			t.Fatalf("读出码字 %v，应为 %v", codewords, helloWorld1M)
+		}
+	}
+}
+
+func TestEncodeQRVersion(t *testing.T) {
+	cases := []struct {
+		data  string
+		level int
+		size  int
+	}{
+		{"01234567890123456789012345678901234567890", QRLevelL, 21}, // 数字模式版本 1-L 最多 41 位
+		{"012345678901234567890123456789012345678901", QRLevelL, 25},
+		{"HTTPS://EXAMPLE.COM/ABC", QRLevelQ, 25},
+		{"https://example.com/abc", QRLevelH, 29},
+	}
+	for _, tc := range cases {
+		bm, err := EncodeQR(tc.data, tc.level)
+		if err != nil || bm.Width != tc.size {
+			t.Errorf("%q 编码为 %d 模块, %v，应为 %d", tc.data, bm.Width, err, tc.size)
+		}
+	}
+
+	if _, err := EncodeQR(string(make([]byte, QRCapacity(QRLevelH)+1)), QRLevelH); err == nil {
+		t.Error("超出容量应返回错误")
+	}
+}
+
+// 容量取决于编码模式和纠错等级
+func TestQRCodewords(t *testing.T) {
+	cases := []struct {
+		data  string
+		level int
+		fits  bool
+	}{
+		{strings.Repeat("1", 7089), QRLevelL, true},
+		{strings.Repeat("1", 7090), QRLevelL, false},
+		{strings.Repeat("A", 4296), QRLevelL, true},
+		{strings.Repeat("A", 4297), QRLevelL, false},
+		{strings.Repeat("a", 1273), QRLevelH, true},
+		{strings.Repeat("a", 1274), QRLevelH, false},
+		{strings.Repeat("1", 3057), QRLevelH, true},
+		{strings.Repeat("1", 3058), QRLevelH, false},
+	}
+	for _, tc := range cases {
+		required, capacity := QRCodewords(tc.data, tc.level)
+		if (required <= capacity) != tc.fits {
+			t.Errorf("%d 个 %q 在等级 %d 需要 %d 个码字，最多 %d 个", len(tc.data), tc.data[0], tc.level, required, capacity)
+		}
+		if _, err := EncodeQR(tc.data, tc.level); (err == nil) != tc.fits {
+			t.Errorf("%d 个 %q 在等级 %d 编码: %v", len(tc.data), tc.data[0], tc.level, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package barcode
+
+// galoisField GF(256) 运算表
+type galoisField struct {
+	exp [512]int
+	log [256]int
+}
+
+// 创建以 primitive 为本原多项式的 GF(256)
+func newGaloisField(primitive int) *galoisField {
+	gf := &galoisField{}
+	x := 1
+	for i := 0; i < 255; i++ {
+		gf.exp[i] = x
+		gf.log[x] = i
+		x <<= 1
+		if x >= 256 {
+			x ^= primitive
+		}
+	}
+	for i := 255; i < 512; i++ {
+		gf.exp[i] = gf.exp[i-255]
+	}
+	return gf
+}
+
+func (gf *galoisField) mul(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gf.exp[gf.log[a]+gf.log[b]]
+}
+
+// 生成多项式 (x - α^first)(x - α^(first+1))...，系数从高次到低次，省略首项 1
+func (gf *galoisField) generator(degree, first int) []int {
+	poly := make([]int, degree)
+	poly[degree-1] = 1
+	root := gf.exp[first%255]
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			poly[j] = gf.mul(poly[j], root)
+			if j+1 < degree {
+				poly[j] ^= poly[j+1]
+			}
+		}
+		root = gf.mul(root, 2)
+	}
+	return poly
+}
+
+// 计算纠错码字（多项式除法余数）
+func (gf *galoisField) ecc(data []int, generator []int) []int {
+	result := make([]int, len(generator))
+	for _, d := range data {
+		factor := d ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gf.mul(generator[i], factor)
+		}
+	}
+	return result
+}
+
+var (
+	qrField         = newGaloisField(0x11D)
+	dataMatrixField = newGaloisField(0x12D)
+)
@@ -0,0 +1,158 @@
+package barcode
+
+import (
+	"fmt"
+)
+
+// UPC-E 由数字系统和校验位决定 6 位数字的奇偶排列（1 为偶校验 G，0 为奇校验 L）
+var upcEParity = [2][10]string{
+	{"111000", "110100", "110010", "110001", "101100", "100110", "100011", "101010", "101001", "100101"},
+	{"000111", "001011", "001101", "001110", "010011", "011001", "011100", "010101", "010110", "011010"},
+}
+
+// NormalizeUPCA 将 11 或 12 位数字规范为带校验位的 12 位 UPC-A
+func NormalizeUPCA(code string) (string, error) {
+	if err := checkDigits("UPC-A", code); err != nil {
+		return "", err
+	}
+	if len(code) != 11 && len(code) != 12 {
+		return "", fmt.Errorf("UPC-A条形码必须是11或12位数字")
+	}
+	return eanComplete("UPC-A", code, 12)
+}
+
+// ExpandUPCE 将 UPC-E（6 位、数字系统+6 位、或再加校验位共 8 位）展开为 12 位 UPC-A
+func ExpandUPCE(code string) (string, error) {
+	if err := checkDigits("UPC-E", code); err != nil {
+		return "", err
+	}
+	input := code
+	switch len(code) {
+	case 6:
+		code = "0" + code
+	case 7, 8:
+	default:
+		return "", fmt.Errorf("UPC-E条形码必须是6、7或8位数字")
+	}
+	ns := code[0]
+	if ns != '0' && ns != '1' {
+		return "", charError("UPC-E", input, 0, "数字系统必须是0或1")
+	}
+
+	d := code[1:7]
+	var body string
+	switch d[5] {
+	case '0', '1', '2':
+		body = d[0:2] + d[5:6] + "0000" + d[2:5]
+	case '3':
+		body = d[0:3] + "00000" + d[3:5]
+	case '4':
+		body = d[0:4] + "00000" + d[4:5]
+	default:
+		body = d[0:5] + "0000" + d[5:6]
+	}
+	upca := string(ns) + body
+	upca += fmt.Sprintf("%d", EANCheckDigit(upca))
+
+	if len(code) == 8 && code[7] != upca[11] {
+		return "", charError("UPC-E", code, 7, fmt.Sprintf("校验码应为 %c", upca[11]))
+	}
+	return upca, nil
+}
+
+// CompressUPCA 将 UPC-A（11 或 12 位）压缩为 8 位 UPC-E（数字系统+6 位+校验位），不可压缩时返回错误
+func CompressUPCA(code string) (string, error) {
+	upca, err := NormalizeUPCA(code)
+	if err != nil {
+		return "", err
+	}
+	if upca[0] != '0' && upca[0] != '1' {
+		return "", fmt.Errorf("UPC-A %s 不能压缩为UPC-E：数字系统必须是0或1", upca)
+	}
+
+	m := upca[1:6]  // 厂商代码
+	p := upca[6:11] // 商品代码
+	var d string
+	switch {
+	case m[3:5] == "00" && m[2] <= '2' && p[0:2] == "00":
+		d = m[0:2] + p[2:5] + m[2:3]
+	case m[3:5] == "00" && p[0:3] == "000":
+		d = m[0:3] + p[3:5] + "3"
+	case m[4] == '0' && p[0:4] == "0000":
+		d = m[0:4] + p[4:5] + "4"
+	case p[0:4] == "0000" && p[4] >= '5':
+		d = m + p[4:5]
+	default:
+		return "", fmt.Errorf("UPC-A %s 不能压缩为UPC-E：厂商代码或商品代码不符合零压缩规则", upca)
+	}
+
+	upce := upca[0:1] + d + upca[11:12]
+	// 展开后必须与原码一致
+	if expanded, err := ExpandUPCE(upce); err != nil || expanded != upca {
+		return "", fmt.Errorf("UPC-A %s 不能压缩为UPC-E", upca)
+	}
+	return upce, nil
+}
+
+// NormalizeUPCE 将 UPC-E 或可压缩的 UPC-A 规范为 8 位 UPC-E
+func NormalizeUPCE(code string) (string, error) {
+	if len(code) == 11 || len(code) == 12 {
+		return CompressUPCA(code)
+	}
+	upca, err := ExpandUPCE(code)
+	if err != nil {
+		return "", err
+	}
+	if len(code) == 6 {
+		code = "0" + code
+	}
+	upce := code[:7] + upca[11:12]
+
+	// 同一个 UPC-A 只有一种规范的 UPC-E 形式
+	canonical, err := CompressUPCA(upca)
+	if err != nil {
+		return "", err
+	}
+	if canonical != upce {
+		return "", fmt.Errorf("UPC-E %s 不是规范的零压缩形式，应为 %s", upce, canonical)
+	}
+	return upce, nil
+}
+
+// 编码 UPC-A（即首位为 0 的 EAN-13），6-8 位输入视为 UPC-E 并展开
+func encodeUPCA(data string) ([]bool, string, error) {
+	if len(data) >= 6 && len(data) <= 8 {
+		upce, err := NormalizeUPCE(data)
+		if err != nil {
+			return nil, "", err
+		}
+		data, _ = ExpandUPCE(upce)
+	}
+	upca, err := NormalizeUPCA(data)
+	if err != nil {
+		return nil, "", err
+	}
+	modules, _, err := encodeEAN13("0" + upca)
+	return modules, upca, err
+}
+
+// 编码 UPC-E
+func encodeUPCE(data string) ([]bool, string, error) {
+	upce, err := NormalizeUPCE(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	modules := appendBits(nil, "101")
+	parity := upcEParity[upce[0]-'0'][upce[7]-'0']
+	for i := 0; i < 6; i++ {
+		d := upce[i+1] - '0'
+		if parity[i] == '1' {
+			modules = appendBits(modules, eanG[d])
+		} else {
+			modules = appendBits(modules, eanL[d])
+		}
+	}
+	modules = appendBits(modules, "010101")
+	return modules, upce, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// 每次批量打印最多的标签数
+const maxBatchItems = 1000
+
+// BatchPrintRequest 批量打印请求，items 与 template + data 二选一，全部标签作为一个任务打印
+type BatchPrintRequest struct {
+	Items []PrintRequest `json:"items"` // 条码列表，各项的 printer 必须为空或与批量请求相同
+
+	Template string                   `json:"template"` // 模板名称
+	Data     []map[string]interface{} `json:"data"`     // 每个标签的模板变量
+
+	Printer string `json:"printer"` // 打印机名称，为空时使用模板指定的或默认打印机
+	Cut     *bool  `json:"cut"`     // 每个标签后是否切纸，省略时使用各项或模板的 cut
+	OnError string `json:"onError"` // 标签无效时：stop（默认，整批不打印）或 continue（跳过无效标签）
+}
+
+// BatchItemResult 批量打印中一个标签的结果
+type BatchItemResult struct {
+	Index   int          `json:"index"`             // 标签在 items 或 data 中的下标，从 0 开始
+	Status  string       `json:"status"`            // success、error 或 skipped（onError=stop 时因其他标签无效未打印）
+	Message string       `json:"message,omitempty"` // 错误信息
+	Code    string       `json:"code,omitempty"`    // 错误代码
+	Detail  interface{}  `json:"detail,omitempty"`  // 错误详情
+	Size    *BarcodeSize `json:"size,omitempty"`    // 条码宽度（CODE128、GS1-128）
+}
+
+// 批量打印处理器
+func batchPrintHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w)
+
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	if r.Method != "POST" {
+		sendError(w, "仅支持POST请求")
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		sendError(w, "读取请求失败")
+		return
+	}
+	var req BatchPrintRequest
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // 保留模板变量中的数字原样
+	if err := dec.Decode(&req); err != nil {
+		sendError(w, "解析JSON失败")
+		return
+	}
+
+	p, cmds, results, err := buildBatchCommands(&req)
+	if err != nil {
+		sendPrintError(w, err)
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Status == "error" {
+			failed++
+		}
+	}
+	if cmds == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(PrintResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("%d个标签中%d个无效，未打印", len(results), failed),
+			Results: results,
+		})
+		return
+	}
+
+	job, err := jobs.Submit(p.Name, fmt.Sprintf("批量 %d个标签", len(results)-failed), cmds)
+	if err != nil {
+		sendPrintError(w, err)
+		return
+	}
+	resp := PrintResponse{Results: results}
+	if failed > 0 {
+		resp.Message = fmt.Sprintf("已加入打印队列，跳过%d个无效标签", failed)
+	}
+	sendJobResponse(w, r, job, resp)
+}
+
+// 生成批量打印的指令和各标签的结果
+// 请求本身有误时返回错误；没有可打印的标签（或 onError=stop 时有无效标签）时指令为 nil
+func buildBatchCommands(req *BatchPrintRequest) (*Printer, []byte, []BatchItemResult, error) {
+	onError := strings.ToLower(req.OnError)
+	if onError == "" {
+		onError = "stop"
+	}
+	if onError != "stop" && onError != "continue" {
+		return nil, nil, nil, fmt.Errorf("onError 必须是 stop 或 continue: %s", req.OnError)
+	}
+
+	var (
+		t     *Template
+		count = len(req.Items)
+		name  = req.Printer
+	)
+	if req.Template != "" {
+		if len(req.Items) > 0 {
+			return nil, nil, nil, fmt.Errorf("items 和 template 只能使用一个")
+		}
+		var err error
+		if t, err = loadTemplate(req.Template); err != nil {
+			return nil, nil, nil, err
+		}
+		if req.Cut != nil {
+			t.Cut = *req.Cut
+		}
+		if name == "" {
+			name = t.Printer
+		}
+		count = len(req.Data)
+	}
+	if count == 0 {
+		return nil, nil, nil, fmt.Errorf("批量打印需要 items，或 template 和 data")
+	}
+	if count > maxBatchItems {
+		return nil, nil, nil, fmt.Errorf("批量打印最多%d个标签，当前%d个", maxBatchItems, count)
+	}
+	p, err := getPrinter(name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var buf bytes.Buffer
+	results := make([]BatchItemResult, count)
+	failed, labels := 0, 0
+	for i := range results {
+		var (
+			cmds   []byte
+			size   *BarcodeSize
+			err    error
+			copies = 1
+		)
+		if t != nil {
+			cmds, err = buildTemplateCommands(p, t, req.Data[i])
+		} else {
+			item := req.Items[i]
+			if item.Printer != "" && item.Printer != p.Name {
+				err = fmt.Errorf("批量打印的标签必须使用同一台打印机 %s: %s", p.Name, item.Printer)
+			} else if item.Sequence != nil {
+				err = fmt.Errorf("批量打印不支持 sequence，请使用 /api/print")
+			} else if err = prepareRequest(&item); err == nil {
+				if req.Cut != nil {
+					item.Cut = *req.Cut
+				}
+				cmds, err = buildCommands(p, &item)
+				copies = item.Copies
+				size = barcodeSize(&item)
+			}
+		}
+
+		results[i].Index = i
+		if err != nil {
+			resp := printErrorResponse(err)
+			results[i].Status, results[i].Message = resp.Status, resp.Message
+			results[i].Code, results[i].Detail = resp.Code, resp.Detail
+			failed++
+			continue
+		}
+		// 展开份数之前检查标签数和数据大小，超出上限立即返回，不为整批数据分配内存
+		labels += copies
+		if labels > maxBatchItems {
+			return nil, nil, nil, fmt.Errorf("批量打印最多%d个标签（含份数），已超过上限", maxBatchItems)
+		}
+		if n := buf.Len() + len(cmds)*copies; n > maxJobData {
+			return nil, nil, nil, &JobSizeError{Size: n, Limit: maxJobData}
+		}
+		results[i].Status, results[i].Size = "success", size
+		buf.Write(bytes.Repeat(cmds, copies))
+	}
+
+	if failed == count {
+		return p, nil, results, nil
+	}
+	if failed > 0 && onError == "stop" {
+		for i := range results {
+			if results[i].Status == "success" {
+				results[i].Status, results[i].Message, results[i].Size = "skipped", "其他标签无效，未打印", nil
+			}
+		}
+		return p, nil, results, nil
+	}
+	return p, buf.Bytes(), results, nil
+}
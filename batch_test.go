@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildBatchCommands(t *testing.T) {
+	templateDir = filepath.Join("testdata", "templates")
+	defer func() { templateDir = "templates" }()
+
+	noCut := false
+	items := []PrintRequest{
+		{BarcodeType: "CODE128", BarcodeData: "SKU-1", Cut: true},
+		{BarcodeType: "EAN13", BarcodeData: "5901234123450"},
+		{BarcodeType: "EAN8", BarcodeData: "9638507", ShowText: true},
+	}
+	skus := []map[string]interface{}{
+		{"name": "Tea", "ean": json.Number("9638507")},
+		{"name": "Coffee", "ean": "5512345"},
+	}
+	cases := []struct {
+		name string
+		req  BatchPrintRequest
+	}{
+		{name: "batch-items", req: BatchPrintRequest{Items: items[:1:1]}},
+		{name: "batch-items-no-cut", req: BatchPrintRequest{Items: []PrintRequest{items[0], items[2]}, Cut: &noCut}},
+		{name: "batch-template", req: BatchPrintRequest{Template: "sku", Data: skus}},
+		{name: "batch-stop", req: BatchPrintRequest{Items: items}},
+		{name: "batch-continue", req: BatchPrintRequest{Items: items, OnError: "continue"}},
+		{name: "batch-template-continue", req: BatchPrintRequest{Template: "sku", Data: append(skus, map[string]interface{}{"name": "x"}), OnError: "Continue"}},
+		{name: "batch-error-printer", req: BatchPrintRequest{Items: []PrintRequest{{BarcodeData: "A", Printer: "other"}}}},
+		{name: "batch-error-empty", req: BatchPrintRequest{Template: "sku"}},
+		{name: "batch-error-both", req: BatchPrintRequest{Items: items, Template: "sku"}},
+		{name: "batch-error-copies", req: BatchPrintRequest{Items: []PrintRequest{{BarcodeData: "A", Copies: 600}, {BarcodeData: "B", Copies: 600}, {BarcodeData: "C"}}}},
+		{name: "batch-error-on-error", req: BatchPrintRequest{Items: items, OnError: "skip"}},
+	}
+
+	printers[firmwarePrinter.Name] = firmwarePrinter
+	defer delete(printers, firmwarePrinter.Name)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.req.Printer = firmwarePrinter.Name
+			var out strings.Builder
+			_, cmds, results, err := buildBatchCommands(&tc.req)
+			if err != nil {
+				out.WriteString("error: " + err.Error() + "\n")
+			}
+			for _, result := range results {
+				line, _ := json.Marshal(result)
+				out.Write(append(line, '\n'))
+			}
+			if cmds != nil {
+				out.WriteString(hex.Dump(cmds))
+			}
+			got := out.String()
+
+			checkGolden(t, tc.name, got)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config 服务配置
+type Config struct {
+	Listen         string          `json:"listen"`         // 监听地址，默认 ":9100"
+	DefaultPrinter string          `json:"defaultPrinter"` // 默认打印机名称
+	Printers       []PrinterConfig `json:"printers"`       // 打印机列表
+	TemplateDir    string          `json:"templateDir"`    // 标签模板目录，默认 "templates"
+	JobFile        string          `json:"jobFile"`        // 打印队列文件，默认 "jobs.jsonl"
+	CounterFile    string          `json:"counterFile"`    // 序列号计数器文件，默认 "counters.json"
+	MaxRetries     int             `json:"maxRetries"`     // 传输错误的最大重试次数，默认 5，负数为不重试
+	RetryDelay     int             `json:"retryDelay"`     // 首次重试前的等待时间（毫秒），之后每次加倍，默认 2000
+	MaxRetryDelay  int             `json:"maxRetryDelay"`  // 重试等待时间上限（毫秒），默认 60000
+}
+
+// PrinterConfig 打印机配置
+type PrinterConfig struct {
+	Name string `json:"name"` // 打印机名称，请求中通过 printer 字段选择
+	Type string `json:"type"` // 传输类型：lpt, tcp, device, serial, virtual
+	Path string `json:"path"` // 端口或设备路径，如 LPT1、/dev/usb/lp0、/dev/ttyS0；虚拟打印机为输出目录
+
+	RasterTypes []string `json:"rasterTypes,omitempty"` // 固件不支持、始终以光栅图打印的条码类型，如 ["QR", "PDF417"]
+	DPI         int      `json:"dpi,omitempty"`         // 打印分辨率，默认 203
+	PaperWidth  int      `json:"paperWidth,omitempty"`  // 纸宽（毫米），58 或 80，默认 80
+	WaitTimeout int      `json:"waitTimeout,omitempty"` // 同步打印（wait=true）时等待开始打印的超时（毫秒），默认 30000
+
+	// 网络打印机（type=tcp）
+	Address        string `json:"address,omitempty"`        // 打印机地址 host:port，端口默认 9100
+	ConnectTimeout int    `json:"connectTimeout,omitempty"` // 连接超时（毫秒），默认 3000
+	WriteTimeout   int    `json:"writeTimeout,omitempty"`   // 写入超时（毫秒），默认 10000
+	// TCP keep-alive 间隔（秒），默认 30。每个任务使用新连接，任务结束即关闭，
+	// 因此只在打印单个较大任务期间检测断开的连接；任务之间不保持连接，断开后的重连由任务队列的重试完成
+	KeepAlive int `json:"keepAlive,omitempty"`
+
+	// 设备文件打印机（type=device）
+	LockTimeout int `json:"lockTimeout,omitempty"` // 等待设备排他锁的超时（毫秒），默认 5000
+
+	// 串口打印机（type=serial）
+	BaudRate    int    `json:"baudRate,omitempty"`    // 波特率，默认 9600
+	DataBits    int    `json:"dataBits,omitempty"`    // 数据位 (5-8)，默认 8
+	Parity      string `json:"parity,omitempty"`      // 校验位：none, odd, even，默认 none
+	StopBits    int    `json:"stopBits,omitempty"`    // 停止位 (1-2)，默认 1
+	FlowControl string `json:"flowControl,omitempty"` // 流控：none, rtscts, xonxoff，默认 none
+}
+
+// 加载配置文件，文件不存在时使用默认配置（LPT1）
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("解析配置文件失败: %v", err)
+		}
+	}
+
+	// 设置默认值
+	if cfg.Listen == "" {
+		cfg.Listen = ":9100"
+	}
+	if cfg.TemplateDir == "" {
+		cfg.TemplateDir = "templates"
+	}
+	if cfg.JobFile == "" {
+		cfg.JobFile = "jobs.jsonl"
+	}
+	if cfg.CounterFile == "" {
+		cfg.CounterFile = "counters.json"
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = 2000
+	}
+	if cfg.MaxRetryDelay <= 0 {
+		cfg.MaxRetryDelay = 60000
+	}
+	if len(cfg.Printers) == 0 {
+		cfg.Printers = []PrinterConfig{{Name: "default", Type: "lpt", Path: "LPT1"}}
+	}
+	for i := range cfg.Printers {
+		if cfg.Printers[i].Name == "" {
+			cfg.Printers[i].Name = fmt.Sprintf("printer%d", i+1)
+		}
+	}
+	if cfg.DefaultPrinter == "" {
+		cfg.DefaultPrinter = cfg.Printers[0].Name
+	}
+
+	return cfg, nil
+}
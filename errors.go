@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+)
+
+// detailedError 结构化错误，响应中附带错误代码和详情
+type detailedError interface {
+	error
+	ErrorCode() string
+}
+
+// CapacityError 数据超出条码容量
+type CapacityError struct {
+	BarcodeType string `json:"barcodeType"` // 条码类型
+	Required    int    `json:"required"`    // 需要的码字数
+	Capacity    int    `json:"capacity"`    // 可用的码字数
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("%s数据过长：需要%d个码字，当前设置最多%d个", e.BarcodeType, e.Required, e.Capacity)
+}
+
+func (e *CapacityError) ErrorCode() string {
+	return "DATA_TOO_LONG"
+}
+
+// WidthError 条码宽度超出打印宽度
+type WidthError struct {
+	Width      int `json:"width"`      // 条码宽度（点）
+	PrintWidth int `json:"printWidth"` // 打印机可打印宽度（点）
+}
+
+func (e *WidthError) Error() string {
+	return fmt.Sprintf("条码宽度%d点超出打印宽度%d点，请减小模块宽度或缩短数据", e.Width, e.PrintWidth)
+}
+
+func (e *WidthError) ErrorCode() string {
+	return "TOO_WIDE"
+}
+
+// BusyError 打印机忙：同步打印时等待超时，任务已取消
+type BusyError struct {
+	Printer   string `json:"printer"`             // 打印机名称
+	Timeout   int    `json:"timeout"`             // 等待时间（毫秒）
+	JobID     string `json:"jobId"`               // 已取消的任务
+	LastError string `json:"lastError,omitempty"` // 任务等待重试时最近一次传输错误
+}
+
+func (e *BusyError) Error() string {
+	if e.LastError != "" {
+		return fmt.Sprintf("打印机 %s 暂时无法打印（%s），等待%d毫秒后已取消任务", e.Printer, e.LastError, e.Timeout)
+	}
+	return fmt.Sprintf("打印机 %s 忙，等待%d毫秒后仍未开始打印，已取消任务", e.Printer, e.Timeout)
+}
+
+func (e *BusyError) ErrorCode() string {
+	return "PRINTER_BUSY"
+}
+
+// JobSizeError 打印数据超出单个任务的大小上限
+type JobSizeError struct {
+	Size  int `json:"size"`  // 打印数据大小（字节）
+	Limit int `json:"limit"` // 单个任务的上限（字节）
+}
+
+func (e *JobSizeError) Error() string {
+	return fmt.Sprintf("打印数据%d字节超出单个任务上限%d字节，请减少份数或分批打印", e.Size, e.Limit)
+}
+
+func (e *JobSizeError) ErrorCode() string {
+	return "JOB_TOO_LARGE"
+}
@@ -0,0 +1,619 @@
+// Package escpos ESC/POS 指令解释器，将打印机字节流渲染到虚拟纸张
+// 支持本服务发出的指令：ESC @、ESC a、ESC E、ESC -、GS !、GS h、GS w、GS H、GS k、GS ( k（PDF417、QR码）、GS v 0、GS V 和文字
+package escpos
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xiaofeiwuuu/tiaoxingma/barcode"
+)
+
+const (
+	esc = 0x1B
+	gs  = 0x1D
+)
+
+// GS k 的条码类型，m=0-6 为 NUL 结尾格式，m=65-73 为带长度格式
+var barcodeTypes = map[byte]string{
+	0: "UPCA", 1: "UPCE", 2: "EAN13", 3: "EAN8", 4: "CODE39", 5: "ITF", 6: "CODABAR",
+	65: "UPCA", 66: "UPCE", 67: "EAN13", 68: "EAN8", 69: "CODE39", 70: "ITF", 71: "CODABAR",
+	72: "CODE93", 73: "CODE128",
+}
+
+// NUL 结尾格式中定长数字条码的最大位数，达到位数即结束
+var fixedDigits = map[byte]int{0: 12, 1: 12, 2: 13, 3: 8}
+
+// 打印机状态
+type state struct {
+	align     int // 0 左对齐，1 居中，2 右对齐
+	barHeight int // 条码高度（点）
+	barWidth  int // 条码模块宽度（点）
+	hri       int // 0 不打印，1 上方，2 下方，3 上下
+
+	charWidth  int // 字符横向放大倍数 (1-8)
+	charHeight int // 字符纵向放大倍数 (1-8)
+	bold       bool
+	underline  bool
+
+	qrSize  int
+	qrLevel string
+	qrData  []byte
+
+	pdfColumns   int // 0 为自动
+	pdfRows      int // 0 为自动
+	pdfWidth     int // 模块宽度（点）
+	pdfRowHeight int // 行高，模块宽度的倍数
+	pdfLevel     int // 纠错等级，-1 为按数据量自动选择
+	pdfData      []byte
+}
+
+func defaultState() state {
+	return state{barHeight: 162, barWidth: 3, charWidth: 1, charHeight: 1, qrSize: 3, qrLevel: "L",
+		pdfWidth: 3, pdfRowHeight: 3, pdfLevel: -1}
+}
+
+// 行缓冲区中的字符及其样式
+type cell struct {
+	c         byte
+	w, h      int
+	bold      bool
+	underline bool
+}
+
+// 解释器
+type interpreter struct {
+	data []byte
+	pos  int
+
+	state
+	line       []cell // 尚未换行打印的文字
+	lineHeight int
+	scale      int // 字体放大倍数
+	paper      *Paper
+	warnings   []string
+}
+
+// Render 解释 ESC/POS 字节流，width 为可打印宽度（点），dpi 为打印分辨率
+func Render(data []byte, width, dpi int) *Receipt {
+	scale := (dpi + 50) / 100
+	if scale < 1 {
+		scale = 1
+	}
+	in := &interpreter{
+		data:       data,
+		state:      defaultState(),
+		lineHeight: int(3.75*float64(dpi)/25.4 + 0.5), // 默认行距约 3.75mm
+		scale:      scale,
+		paper:      newPaper(width),
+	}
+	in.run()
+	if len(in.line) > 0 {
+		in.flush()
+	}
+	return &Receipt{Image: in.paper.Bitmap, Cuts: in.paper.Cuts, Warnings: in.warnings}
+}
+
+// 读取 n 个字节，数据不足时返回 nil
+func (in *interpreter) take(n int) []byte {
+	if in.pos+n > len(in.data) {
+		return nil
+	}
+	b := in.data[in.pos : in.pos+n]
+	in.pos += n
+	return b
+}
+
+func (in *interpreter) run() {
+	for in.pos < len(in.data) {
+		start := in.pos
+		c := in.data[in.pos]
+		in.pos++
+		switch {
+		case c == esc:
+			in.escCommand(start)
+		case c == gs:
+			in.gsCommand(start)
+		case c == '\n':
+			in.flush()
+		case c >= 0x20:
+			in.char(c)
+		}
+		// 其他控制字符忽略
+	}
+}
+
+// ESC 指令
+func (in *interpreter) escCommand(start int) {
+	b := in.take(1)
+	if b == nil {
+		in.incomplete(start, "ESC")
+		return
+	}
+	switch b[0] {
+	case '@':
+		in.state = defaultState()
+		in.line = nil
+	case 'a':
+		n := in.take(1)
+		if n == nil {
+			in.incomplete(start, "ESC a")
+		} else if n[0]%48 > 2 || (n[0] > 2 && n[0] < 48) {
+			in.warnAt(start, "ESC a 参数无效: %d", n[0])
+		} else {
+			in.align = int(n[0] % 48)
+		}
+	case 'E', '-':
+		n := in.take(1)
+		if n == nil {
+			in.incomplete(start, "ESC "+string(b[0]))
+		} else if b[0] == 'E' {
+			in.bold = n[0]&1 != 0
+		} else {
+			in.underline = n[0]%48 != 0
+		}
+	default:
+		in.warnAt(start, "未知指令 ESC 0x%02X，已跳过", b[0])
+	}
+}
+
+// GS 指令
+func (in *interpreter) gsCommand(start int) {
+	b := in.take(1)
+	if b == nil {
+		in.incomplete(start, "GS")
+		return
+	}
+	switch b[0] {
+	case 'h':
+		n := in.take(1)
+		if n == nil {
+			in.incomplete(start, "GS h")
+		} else if n[0] == 0 {
+			in.warnAt(start, "GS h 条码高度不能为 0")
+		} else {
+			in.barHeight = int(n[0])
+		}
+	case 'w':
+		n := in.take(1)
+		if n == nil {
+			in.incomplete(start, "GS w")
+		} else if n[0] < 1 || n[0] > 6 {
+			in.warnAt(start, "GS w 模块宽度必须是 1-6: %d", n[0])
+		} else {
+			in.barWidth = int(n[0])
+		}
+	case 'H':
+		n := in.take(1)
+		if n == nil {
+			in.incomplete(start, "GS H")
+		} else if n[0]%48 > 3 || (n[0] > 3 && n[0] < 48) {
+			in.warnAt(start, "GS H 参数无效: %d", n[0])
+		} else {
+			in.hri = int(n[0] % 48)
+		}
+	case '!':
+		n := in.take(1)
+		if n == nil {
+			in.incomplete(start, "GS !")
+		} else if n[0]>>4 > 7 || n[0]&0x0F > 7 {
+			in.warnAt(start, "GS ! 参数无效: 0x%02X", n[0])
+		} else {
+			in.charWidth, in.charHeight = int(n[0]>>4)+1, int(n[0]&0x0F)+1
+		}
+	case 'k':
+		in.barcode(start)
+	case '(':
+		in.function(start)
+	case 'v':
+		in.raster(start)
+	case 'V':
+		in.cut(start)
+	default:
+		in.warnAt(start, "未知指令 GS 0x%02X，已跳过", b[0])
+	}
+}
+
+// 记录警告，start 为指令的起始位置
+func (in *interpreter) warnAt(start int, format string, args ...interface{}) {
+	in.warnings = append(in.warnings, fmt.Sprintf("第%d字节：", start+1)+fmt.Sprintf(format, args...))
+}
+
+// 指令不完整：记录警告并结束解释
+func (in *interpreter) incomplete(start int, name string) {
+	in.warnAt(start, "%s 指令不完整", name)
+	in.pos = len(in.data)
+}
+
+// 条码、光栅图只能在行首打印，缓冲区有文字时打印机忽略该指令
+func (in *interpreter) atLineStart(start int, name string) bool {
+	if len(in.line) > 0 {
+		in.warnAt(start, "%s 不在行首，已被忽略", name)
+		return false
+	}
+	return true
+}
+
+// 行缓冲区中文字的宽度（点）
+func (in *interpreter) lineWidth() int {
+	w := 0
+	for _, c := range in.line {
+		w += barcode.FontWidth * in.scale * c.w
+	}
+	return w
+}
+
+// 追加文字，超出行宽时自动换行
+func (in *interpreter) char(c byte) {
+	if c > 126 {
+		c = '?'
+	}
+	if in.lineWidth()+barcode.FontWidth*in.scale*in.charWidth > in.paper.Width {
+		in.flush()
+	}
+	in.line = append(in.line, cell{c: c, w: in.charWidth, h: in.charHeight, bold: in.bold, underline: in.underline})
+}
+
+// 打印缓冲区中的文字并换行（LF），行高取默认行距和最高字符中的较大者
+func (in *interpreter) flush() {
+	height := in.lineHeight
+	if len(in.line) > 0 {
+		top := 0
+		for _, c := range in.line {
+			top = max(top, barcode.FontHeight*in.scale*c.h)
+		}
+		height = max(height, top+in.lineHeight-barcode.FontHeight*in.scale)
+
+		x := in.alignX(in.lineWidth())
+		in.paper.grow(in.paper.y + top)
+		for _, c := range in.line {
+			sx, sy := in.scale*c.w, in.scale*c.h
+			y := in.paper.y + top - barcode.FontHeight*sy // 字符底部对齐
+			in.paper.DrawTextSize(x, y, string(c.c), sx, sy)
+			if c.bold {
+				in.paper.DrawTextSize(x+in.scale, y, string(c.c), sx, sy) // 加粗：横向错开重复打印
+			}
+			if c.underline {
+				in.paper.FillRect(x, in.paper.y+top-in.scale, barcode.FontWidth*sx, in.scale, true)
+			}
+			x += barcode.FontWidth * sx
+		}
+		in.line = nil
+	}
+	in.paper.feed(height)
+}
+
+// 按对齐方式计算宽度为 w 的内容的横坐标
+func (in *interpreter) alignX(w int) int {
+	switch in.align {
+	case 1:
+		return (in.paper.Width - w) / 2
+	case 2:
+		return in.paper.Width - w
+	}
+	return 0
+}
+
+// GS k 打印条码
+func (in *interpreter) barcode(start int) {
+	mb := in.take(1)
+	if mb == nil {
+		in.incomplete(start, "GS k")
+		return
+	}
+	m := mb[0]
+
+	var data []byte
+	switch {
+	case m <= 6:
+		// NUL 结尾；定长数字条码达到位数即结束
+		end := in.pos
+		if digits, ok := fixedDigits[m]; ok {
+			for end < len(in.data) && end-in.pos < digits && in.data[end] >= '0' && in.data[end] <= '9' {
+				end++
+			}
+		} else {
+			for end < len(in.data) && in.data[end] != 0 {
+				end++
+			}
+			if end == len(in.data) {
+				in.incomplete(start, "GS k（缺少 NUL 结束符）")
+				return
+			}
+		}
+		data = in.data[in.pos:end]
+		in.pos = end
+		if in.pos < len(in.data) && in.data[in.pos] == 0 {
+			in.pos++
+		}
+	case m >= 65 && m <= 73:
+		n := in.take(1)
+		if n == nil {
+			in.incomplete(start, "GS k")
+			return
+		}
+		if data = in.take(int(n[0])); data == nil {
+			in.incomplete(start, "GS k")
+			return
+		}
+	default:
+		in.warnAt(start, "GS k 不支持的条码类型 m=%d，已跳过", m)
+		return
+	}
+
+	if !in.atLineStart(start, "GS k") {
+		return
+	}
+	barcodeType := barcodeTypes[m]
+	var code *barcode.Code
+	var err error
+	if barcodeType == "CODE128" {
+		code, err = barcode.ParseCode128Payload(data)
+	} else {
+		if barcodeType == "CODE39" {
+			data = []byte(strings.Trim(string(data), "*")) // 起始符/终止符由打印机添加
+		}
+		code, err = barcode.Encode(barcodeType, string(data))
+	}
+	if err != nil {
+		in.warnAt(start, "GS k %s 数据无效，打印机不会打印：%v", barcodeType, err)
+		return
+	}
+
+	// 固件条码不画保护框，也不留静区
+	code.BearerBars = false
+	bm := code.Render(in.barWidth, in.barHeight, 0)
+	if bm.Width > in.paper.Width {
+		in.warnAt(start, "GS k %s 条码宽度%d点超出打印宽度%d点，打印机不会打印", barcodeType, bm.Width, in.paper.Width)
+		return
+	}
+
+	x := in.alignX(bm.Width)
+	gap := 2 * in.scale
+	hri := func() {
+		in.paper.text(x+(bm.Width-barcode.TextWidth(code.Text, in.scale))/2, code.Text, in.scale)
+		in.paper.feed(barcode.FontHeight * in.scale)
+	}
+	if in.hri&1 != 0 {
+		hri()
+		in.paper.feed(gap)
+	}
+	in.paper.print(bm, x)
+	if in.hri&2 != 0 {
+		in.paper.feed(gap)
+		hri()
+	}
+}
+
+// GS ( k 二维码功能，支持 PDF417（cn=48）和 QR 码（cn=49）
+func (in *interpreter) function(start int) {
+	b := in.take(1)
+	if b == nil {
+		in.incomplete(start, "GS (")
+		return
+	}
+	if b[0] != 'k' {
+		// 其他 GS ( 功能按长度跳过
+		size := in.take(2)
+		if size == nil || in.take(int(size[0])|int(size[1])<<8) == nil {
+			in.incomplete(start, "GS (")
+			return
+		}
+		in.warnAt(start, "未知指令 GS ( %c，已跳过", b[0])
+		return
+	}
+
+	size := in.take(2)
+	if size == nil {
+		in.incomplete(start, "GS ( k")
+		return
+	}
+	params := in.take(int(size[0]) | int(size[1])<<8)
+	if params == nil || len(params) < 2 {
+		in.incomplete(start, "GS ( k")
+		return
+	}
+	cn, fn, args := params[0], params[1], params[2:]
+
+	if cn == 48 {
+		in.pdf417(start, fn, args)
+		return
+	}
+	if cn != 49 {
+		// 其他符号（如 MaxiCode）不模拟，打印指令记录警告，纸张上不输出
+		if fn == 81 {
+			in.warnAt(start, "GS ( k cn=%d 的二维码不支持模拟，不会输出到图片", cn)
+		}
+		return
+	}
+	switch fn {
+	case 65: // 选择模型
+	case 67:
+		if len(args) > 0 && args[0] >= 1 && args[0] <= 16 {
+			in.qrSize = int(args[0])
+		}
+	case 69:
+		if len(args) > 0 && args[0] >= 48 && args[0] <= 51 {
+			in.qrLevel = string("LMQH"[args[0]-48])
+		}
+	case 80:
+		if len(args) > 0 {
+			in.qrData = append([]byte(nil), args[1:]...)
+		}
+	case 81:
+		if !in.atLineStart(start, "GS ( k 打印QR码") {
+			return
+		}
+		in.printQR(start)
+	}
+}
+
+func (in *interpreter) printQR(start int) {
+	if len(in.qrData) == 0 {
+		in.warnAt(start, "QR码没有存储数据，打印机不会打印")
+		return
+	}
+	level, _ := barcode.ParseQRLevel(in.qrLevel)
+	matrix, err := barcode.EncodeQR(string(in.qrData), level)
+	if err != nil {
+		in.warnAt(start, "QR码数据无效，打印机不会打印：%v", err)
+		return
+	}
+	bm := matrix.Scale(in.qrSize, 0)
+	if bm.Width > in.paper.Width {
+		in.warnAt(start, "QR码宽度%d点超出打印宽度%d点，打印机不会打印", bm.Width, in.paper.Width)
+		return
+	}
+	in.paper.print(bm, in.alignX(bm.Width))
+}
+
+// GS ( k cn=48 PDF417：设置列数(65)、行数(66)、模块宽度(67)、行高(68)、纠错等级(69)，存储数据(80)，打印(81)
+func (in *interpreter) pdf417(start int, fn byte, args []byte) {
+	arg := func(lo, hi byte) (int, bool) {
+		if len(args) == 0 || args[0] < lo || args[0] > hi {
+			in.warnAt(start, "GS ( k PDF417 功能 %d 参数无效", fn)
+			return 0, false
+		}
+		return int(args[0]), true
+	}
+	switch fn {
+	case 65:
+		if n, ok := arg(0, 30); ok {
+			in.pdfColumns = n
+		}
+	case 66:
+		if n, ok := arg(0, 90); ok && n != 1 && n != 2 {
+			in.pdfRows = n
+		} else if ok {
+			in.warnAt(start, "GS ( k PDF417 功能 %d 参数无效", fn)
+		}
+	case 67:
+		if n, ok := arg(2, 8); ok {
+			in.pdfWidth = n
+		}
+	case 68:
+		if n, ok := arg(2, 8); ok {
+			in.pdfRowHeight = n
+		}
+	case 69:
+		// m=48 按等级设置（n=48-56）；m=49 按数据码字的比例设置，按数据量自动选择等级
+		switch {
+		case len(args) == 2 && args[0] == 48 && args[1] >= 48 && args[1] <= 56:
+			in.pdfLevel = int(args[1] - 48)
+		case len(args) == 2 && args[0] == 49 && args[1] >= 1 && args[1] <= 40:
+			in.pdfLevel = -1
+		default:
+			in.warnAt(start, "GS ( k PDF417 纠错等级参数无效")
+		}
+	case 70: // 选择标准或截短格式，不模拟截短格式
+	case 80:
+		if len(args) > 0 {
+			in.pdfData = append([]byte(nil), args[1:]...)
+		}
+	case 81:
+		if !in.atLineStart(start, "GS ( k 打印PDF417") {
+			return
+		}
+		in.printPDF417(start)
+	}
+}
+
+func (in *interpreter) printPDF417(start int) {
+	if len(in.pdfData) == 0 {
+		in.warnAt(start, "PDF417没有存储数据，打印机不会打印")
+		return
+	}
+	data := string(in.pdfData)
+	level := in.pdfLevel
+	if level < 0 {
+		level = barcode.PDF417AutoLevel(data)
+	}
+	symbol, err := barcode.EncodePDF417(data, in.pdfColumns, in.pdfRows, level, in.pdfRowHeight)
+	if err != nil {
+		in.warnAt(start, "PDF417数据无效，打印机不会打印：%v", err)
+		return
+	}
+	bm := symbol.Scale(in.pdfWidth, 0)
+	if bm.Width > in.paper.Width {
+		in.warnAt(start, "PDF417宽度%d点超出打印宽度%d点，打印机不会打印", bm.Width, in.paper.Width)
+		return
+	}
+	in.paper.print(bm, in.alignX(bm.Width))
+}
+
+// GS v 0 m xL xH yL yH d1...dk 光栅位图
+func (in *interpreter) raster(start int) {
+	header := in.take(6)
+	if header == nil || header[0] != '0' {
+		in.incomplete(start, "GS v 0")
+		return
+	}
+	m := header[1] % 48
+	widthBytes := int(header[2]) | int(header[3])<<8
+	height := int(header[4]) | int(header[5])<<8
+	data := in.take(widthBytes * height)
+	if data == nil {
+		in.incomplete(start, "GS v 0")
+		return
+	}
+	if m > 3 {
+		in.warnAt(start, "GS v 0 参数无效: m=%d", header[1])
+		return
+	}
+	if !in.atLineStart(start, "GS v 0") {
+		return
+	}
+
+	bm := barcode.NewBitmap(widthBytes*8, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < widthBytes*8; x++ {
+			if data[y*widthBytes+x/8]&(0x80>>uint(x%8)) != 0 {
+				bm.Set(x, y, true)
+			}
+		}
+	}
+	// m=1 倍宽，m=2 倍高，m=3 倍宽倍高
+	sx, sy := 1+int(m&1), 1+int(m>>1)
+	if sx > 1 || sy > 1 {
+		scaled := barcode.NewBitmap(bm.Width*sx, bm.Height*sy)
+		for y := 0; y < bm.Height; y++ {
+			for x := 0; x < bm.Width; x++ {
+				if bm.Get(x, y) {
+					scaled.FillRect(x*sx, y*sy, sx, sy, true)
+				}
+			}
+		}
+		bm = scaled
+	}
+	if bm.Width > in.paper.Width {
+		in.warnAt(start, "GS v 0 图像宽度%d点超出打印宽度%d点，超出部分不会打印", bm.Width, in.paper.Width)
+	}
+	in.paper.print(bm, in.alignX(min(bm.Width, in.paper.Width)))
+}
+
+// GS V 切纸
+func (in *interpreter) cut(start int) {
+	m := in.take(1)
+	if m == nil {
+		in.incomplete(start, "GS V")
+		return
+	}
+	switch m[0] {
+	case 0, 1, '0', '1':
+	case 65, 66:
+		// 走纸 n 个单位后切纸
+		n := in.take(1)
+		if n == nil {
+			in.incomplete(start, "GS V")
+			return
+		}
+		in.paper.feed(int(n[0]))
+	default:
+		in.warnAt(start, "GS V 参数无效: %d", m[0])
+		return
+	}
+	if len(in.line) > 0 {
+		in.flush()
+	}
+	in.paper.cut()
+}
@@ -0,0 +1,311 @@
+package escpos
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xiaofeiwuuu/tiaoxingma/barcode"
+)
+
+// 读取第 y 行从第一个深色点到最后一个深色点之间的模块，moduleWidth 为每个模块的点数
+func scanModules(t *testing.T, img *barcode.Bitmap, y, moduleWidth int) []bool {
+	t.Helper()
+	left, right := -1, -1
+	for x := 0; x < img.Width; x++ {
+		if img.Get(x, y) {
+			if left < 0 {
+				left = x
+			}
+			right = x
+		}
+	}
+	if left < 0 {
+		t.Fatalf("第 %d 行没有打印内容", y)
+	}
+	if (right-left+1)%moduleWidth != 0 {
+		t.Fatalf("条码宽度 %d 点不是模块宽度 %d 的整数倍", right-left+1, moduleWidth)
+	}
+	var modules []bool
+	for x := left; x <= right; x += moduleWidth {
+		for i := 1; i < moduleWidth; i++ {
+			if img.Get(x+i, y) != img.Get(x, y) {
+				t.Fatalf("第 %d 行 x=%d 的模块宽度不一致", y, x)
+			}
+		}
+		modules = append(modules, img.Get(x, y))
+	}
+	return modules
+}
+
+// EAN-13 左侧 A 组（奇）编码，B 组为 C 组的逆序，C 组为 A 组取反
+var eanA = [10]string{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"}
+
+// 首位数字决定左侧 6 位的 A/B 组合
+var eanFirst = [10]string{"AAAAAA", "AABABB", "AABBAB", "AABBBA", "ABAABB", "ABBAAB", "ABBBAA", "ABABAB", "ABABBA", "ABBABA"}
+
+// 从模块解码 EAN-13，并验证校验码
+func decodeEAN13(t *testing.T, modules []bool) string {
+	t.Helper()
+	var bits strings.Builder
+	for _, m := range modules {
+		if m {
+			bits.WriteByte('1')
+		} else {
+			bits.WriteByte('0')
+		}
+	}
+	s := bits.String()
+	if len(s) != 95 || s[:3] != "101" || s[45:50] != "01010" || s[92:] != "101" {
+		t.Fatalf("EAN-13 保护符或长度错误: %s", s)
+	}
+
+	invert := func(p string) string {
+		return strings.Map(func(r rune) rune { return '0' + '1' - r }, p)
+	}
+	reverse := func(p string) string {
+		b := []byte(p)
+		for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+		return string(b)
+	}
+	digit := func(p string, sets string) (int, byte) {
+		for d := 0; d < 10; d++ {
+			for _, set := range sets {
+				code := map[rune]string{'A': eanA[d], 'B': reverse(invert(eanA[d])), 'C': invert(eanA[d])}[set]
+				if p == code {
+					return d, byte(set)
+				}
+			}
+		}
+		t.Fatalf("无法识别的 EAN 字符 %s", p)
+		return 0, 0
+	}
+
+	var digits []byte
+	var parity []byte
+	for i := 0; i < 6; i++ {
+		d, set := digit(s[3+7*i:10+7*i], "AB")
+		digits = append(digits, byte('0'+d))
+		parity = append(parity, set)
+	}
+	for i := 0; i < 6; i++ {
+		d, _ := digit(s[50+7*i:57+7*i], "C")
+		digits = append(digits, byte('0'+d))
+	}
+	first := -1
+	for d, p := range eanFirst {
+		if p == string(parity) {
+			first = d
+		}
+	}
+	if first < 0 {
+		t.Fatalf("EAN-13 左侧奇偶组合 %s 无效", parity)
+	}
+	code := string(rune('0'+first)) + string(digits)
+
+	sum := 0
+	for i := 0; i < 12; i++ {
+		w := 1 + 2*(i%2)
+		sum += int(code[i]-'0') * w
+	}
+	if int(code[12]-'0') != (10-sum%10)%10 {
+		t.Fatalf("EAN-13 %s 校验码错误", code)
+	}
+	return code
+}
+
+func gsk(m byte, data string) []byte {
+	return append([]byte{gs, 'k', m, byte(len(data))}, data...)
+}
+
+// GS k 的 EAN-13/UPC-A 渲染后解码回原始数据
+func TestRenderEAN13(t *testing.T) {
+	cases := []struct {
+		cmd  []byte
+		want string
+	}{
+		{gsk(67, "4006381333931"), "4006381333931"},
+		{gsk(67, "590123412345"), "5901234123457"}, // 打印机补校验码
+		{append([]byte{gs, 'k', 2}, "9780306406157\x00"...), "9780306406157"},
+		{gsk(65, "03600029145"), "0036000291452"}, // UPC-A 为首位 0 的 EAN-13
+	}
+	for _, tc := range cases {
+		data := append([]byte{esc, '@', gs, 'w', 2, gs, 'h', 40}, tc.cmd...)
+		r := Render(data, 576, 203)
+		if len(r.Warnings) > 0 {
+			t.Fatalf("%q: %v", tc.cmd, r.Warnings)
+		}
+		if got := decodeEAN13(t, scanModules(t, r.Image, 20, 2)); got != tc.want {
+			t.Errorf("%q 解码为 %s，应为 %s", tc.cmd, got, tc.want)
+		}
+	}
+}
+
+// 打印机数据（GS k 73）与直接编码的 CODE128 模块一致
+func TestRenderCode128(t *testing.T) {
+	for _, text := range []string{"No.12345678", "ABC-001", "A\tB", "1234a5678"} {
+		c, err := barcode.EncodeCode128(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := barcode.Encode("CODE128", text)
+
+		data := append([]byte{esc, 'a', 1, gs, 'w', 3}, gsk(73, string(c.Payload()))...)
+		r := Render(data, 576, 203)
+		if len(r.Warnings) > 0 {
+			t.Fatalf("%q: %v", text, r.Warnings)
+		}
+		got := scanModules(t, r.Image, 20, 3)
+		if len(got) != len(want.Modules) {
+			t.Fatalf("%q 渲染为 %d 个模块，应为 %d 个", text, len(got), len(want.Modules))
+		}
+		for i := range got {
+			if got[i] != want.Modules[i] {
+				t.Fatalf("%q 第 %d 个模块不一致", text, i)
+			}
+		}
+		// 居中打印
+		left := 0
+		for !r.Image.Get(left, 20) {
+			left++
+		}
+		if w := len(got) * 3; left != (576-w)/2 {
+			t.Errorf("%q 居中时左边距为 %d，应为 %d", text, left, (576-w)/2)
+		}
+	}
+}
+
+// GS ( k 存储并打印 QR 码，逐模块与编码结果比较
+func TestRenderQR(t *testing.T) {
+	fn := func(params ...byte) []byte {
+		return append([]byte{gs, '(', 'k', byte(len(params)), byte(len(params) >> 8)}, params...)
+	}
+	text := "https://example.com/item?id=42"
+	var data []byte
+	data = append(data, fn(49, 65, 50, 0)...)
+	data = append(data, fn(49, 67, 4)...)
+	data = append(data, fn(49, 69, 49)...) // M
+	data = append(data, fn(append([]byte{49, 80, 48}, text...)...)...)
+	data = append(data, fn(49, 81, 48)...)
+
+	r := Render(data, 576, 203)
+	if len(r.Warnings) > 0 {
+		t.Fatal(r.Warnings)
+	}
+	want, err := barcode.EncodeQR(text, barcode.QRLevelM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Image.Height != want.Height*4 {
+		t.Fatalf("QR码高度为 %d 点，应为 %d", r.Image.Height, want.Height*4)
+	}
+	for y := 0; y < want.Height; y++ {
+		for x := 0; x < want.Width; x++ {
+			if r.Image.Get(x*4+1, y*4+1) != want.Get(x, y) {
+				t.Fatalf("QR码模块 (%d,%d) 不一致", x, y)
+			}
+		}
+	}
+}
+
+// GS ( k cn=48 存储并打印 PDF417，逐点与编码结果比较
+func TestRenderPDF417(t *testing.T) {
+	fn := func(params ...byte) []byte {
+		return append([]byte{gs, '(', 'k', byte(len(params)), byte(len(params) >> 8)}, params...)
+	}
+	text := "PDF417 emulator\n\x00\x01 123456789012345"
+	cases := []struct {
+		setup                           []byte
+		columns, rows, level, rowHeight int
+		moduleWidth                     int
+	}{
+		{nil, 0, 0, barcode.PDF417AutoLevel(text), 3, 3}, // 默认参数
+		{append(append(append(fn(48, 65, 4), fn(48, 67, 2)...), fn(48, 68, 5)...), fn(48, 69, 48, 49)...), 4, 0, 1, 5, 2},
+		{append(fn(48, 66, 12), fn(48, 69, 48, 48)...), 0, 12, 0, 3, 3},
+	}
+	for _, tc := range cases {
+		data := append([]byte{esc, 'a', 1}, tc.setup...)
+		data = append(data, fn(append([]byte{48, 80, 48}, text...)...)...)
+		data = append(data, fn(48, 81, 48)...)
+
+		r := Render(data, 576, 203)
+		if len(r.Warnings) > 0 {
+			t.Fatal(r.Warnings)
+		}
+		symbol, err := barcode.EncodePDF417(text, tc.columns, tc.rows, tc.level, tc.rowHeight)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := symbol.Scale(tc.moduleWidth, 0)
+		if r.Image.Height != want.Height {
+			t.Fatalf("PDF417 高度为 %d 点，应为 %d", r.Image.Height, want.Height)
+		}
+		left := (576 - want.Width) / 2
+		for y := 0; y < r.Image.Height; y++ {
+			for x := 0; x < r.Image.Width; x++ {
+				if r.Image.Get(x, y) != want.Get(x-left, y) {
+					t.Fatalf("PDF417 在 (%d,%d) 不一致", x, y)
+				}
+			}
+		}
+	}
+}
+
+// GS v 0 光栅图逐点还原，m=3 时倍宽倍高
+func TestRenderRaster(t *testing.T) {
+	rows := [][]byte{{0xF0, 0x0F}, {0xAA, 0x55}, {0x81, 0x18}}
+	for _, m := range []byte{0, 3} {
+		data := []byte{gs, 'v', '0', m, 2, 0, byte(len(rows)), 0}
+		for _, row := range rows {
+			data = append(data, row...)
+		}
+		r := Render(data, 384, 203)
+		if len(r.Warnings) > 0 {
+			t.Fatal(r.Warnings)
+		}
+		s := 1 + int(m&1)
+		if r.Image.Height != len(rows)*s {
+			t.Fatalf("m=%d 时高度为 %d，应为 %d", m, r.Image.Height, len(rows)*s)
+		}
+		for y := 0; y < r.Image.Height; y++ {
+			for x := 0; x < r.Image.Width; x++ {
+				want := x < 16*s && rows[y/s][x/s/8]&(0x80>>uint(x/s%8)) != 0
+				if r.Image.Get(x, y) != want {
+					t.Fatalf("m=%d 时 (%d,%d) 应为 %v", m, x, y, want)
+				}
+			}
+		}
+	}
+}
+
+// 打印机不会打印的指令记录警告，纸张上没有内容
+func TestRenderWarnings(t *testing.T) {
+	cases := []struct {
+		data []byte
+		want string
+	}{
+		{gsk(67, "4006381333932"), "数据无效"},
+		{append([]byte{gs, 'w', 6}, gsk(73, "{B"+strings.Repeat("W", 40))...), "超出打印宽度"},
+		{append([]byte("text"), gsk(72, "ABC")...), "不在行首"},
+		{[]byte{gs, 'k', 80}, "不支持的条码类型"},
+		{[]byte{gs, 'k', 67, 13, '4'}, "指令不完整"},
+		{[]byte{gs, '(', 'k', 3, 0, 48, 81, 48}, "PDF417没有存储数据"},
+		{[]byte{gs, '(', 'k', 3, 0, 48, 66, 2}, "参数无效"},
+		{[]byte{gs, '(', 'k', 3, 0, 50, 81, 48}, "cn=50"},
+	}
+	for _, tc := range cases {
+		r := Render(tc.data, 384, 203)
+		if len(r.Warnings) != 1 || !strings.Contains(r.Warnings[0], tc.want) {
+			t.Errorf("%q 的警告为 %v，应包含 %q", tc.data, r.Warnings, tc.want)
+			continue
+		}
+		for y := 0; y < r.Image.Height; y++ {
+			for x := 0; x < r.Image.Width; x++ {
+				if r.Image.Get(x, y) && !strings.HasPrefix(string(tc.data), "text") {
+					t.Fatalf("%q 不应打印任何内容", tc.data)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package escpos
+
+import (
+	"image/png"
+	"io"
+
+	"github.com/xiaofeiwuuu/tiaoxingma/barcode"
+)
+
+// Paper 虚拟纸张，宽度固定，内容自上而下追加，高度随打印增长
+type Paper struct {
+	*barcode.Bitmap
+	Cuts []int // 切纸位置（点）
+
+	y int // 当前打印位置
+}
+
+func newPaper(width int) *Paper {
+	return &Paper{Bitmap: barcode.NewBitmap(width, 0)}
+}
+
+// 纸张至少增长到 height 点高
+func (p *Paper) grow(height int) {
+	if height > p.Height {
+		p.Pix = append(p.Pix, make([]bool, (height-p.Height)*p.Width)...)
+		p.Height = height
+	}
+}
+
+// 走纸 n 点
+func (p *Paper) feed(n int) {
+	p.y += n
+	p.grow(p.y)
+}
+
+// 在当前行打印位图并走纸
+func (p *Paper) print(bm *barcode.Bitmap, x int) {
+	p.grow(p.y + bm.Height)
+	p.Draw(bm, x, p.y)
+	p.y += bm.Height
+}
+
+// 在当前位置打印一行文字（不走纸）
+func (p *Paper) text(x int, s string, scale int) {
+	p.grow(p.y + barcode.FontHeight*scale)
+	p.DrawText(x, p.y, s, scale)
+}
+
+// 记录切纸位置
+func (p *Paper) cut() {
+	p.Cuts = append(p.Cuts, p.y)
+}
+
+// Receipt 渲染结果
+type Receipt struct {
+	Image    *barcode.Bitmap
+	Cuts     []int    // 切纸位置（点）
+	Warnings []string // 无法识别或被打印机忽略的指令
+}
+
+// WritePNG 将小票输出为 PNG，切纸位置以虚线表示
+func (r *Receipt) WritePNG(w io.Writer) error {
+	img := barcode.NewBitmap(r.Image.Width, r.Image.Height+1)
+	img.Draw(r.Image, 0, 0)
+	for _, y := range r.Cuts {
+		for x := 0; x < img.Width; x += 8 {
+			img.FillRect(x, y, 4, 1, true)
+		}
+	}
+	return png.Encode(w, img.Image())
+}
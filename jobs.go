@@ -0,0 +1,675 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 任务状态
+const (
+	JobQueued    = "queued"    // 等待打印
+	JobPrinting  = "printing"  // 正在发送到打印机
+	JobRetrying  = "retrying"  // 传输错误，等待重试
+	JobDone      = "done"      // 打印完成
+	JobFailed    = "failed"    // 打印失败，重试次数用尽或错误不可重试（死信）
+	JobCancelled = "cancelled" // 已取消
+)
+
+const (
+	jobRetention     = 7 * 24 * time.Hour // 已完成任务的保留时间
+	jobCompactWrites = 1000               // 追加多少条记录后压缩队列文件
+	jobCompactBytes  = 64 << 20           // 追加的数据超过此大小（且超过压缩后的文件大小）时压缩队列文件
+	maxJobData       = 16 << 20           // 单个任务的打印数据上限，base64 后仍远小于 maxJobLine
+	maxJobLine       = 64 << 20           // 恢复时队列文件中一行的上限，超出的行被忽略
+)
+
+// Job 打印任务
+type Job struct {
+	ID         string     `json:"id"`
+	Printer    string     `json:"printer"`
+	Status     string     `json:"status"`
+	Summary    string     `json:"summary"` // 任务内容摘要，如 "CODE128 ABC-123"
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"` // 最近一次错误
+
+	Attempts    int        `json:"attempts,omitempty"`    // 已尝试打印的次数
+	NextRetryAt *time.Time `json:"nextRetryAt,omitempty"` // 下次重试时间（status=retrying）
+	Partial     bool       `json:"partial,omitempty"`     // 最近一次打印已发送部分数据后失败，打印机可能已打印部分内容
+
+	Data []byte `json:"data,omitempty"` // ESC/POS 指令，只保存在队列文件中
+}
+
+// RetryPolicy 传输错误的重试策略，每次重试前的等待时间加倍
+type RetryPolicy struct {
+	MaxRetries int           // 最大重试次数，0 为不重试
+	Delay      time.Duration // 首次重试前的等待时间
+	MaxDelay   time.Duration // 等待时间上限
+}
+
+// 第 n 次重试前的等待时间
+func (r RetryPolicy) backoff(n int) time.Duration {
+	d := r.Delay
+	for i := 1; i < n && d < r.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, r.MaxDelay)
+}
+
+// 打印任务的重试策略
+func (c *Config) retryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: max(c.MaxRetries, 0),
+		Delay:      time.Duration(c.RetryDelay) * time.Millisecond,
+		MaxDelay:   time.Duration(c.MaxRetryDelay) * time.Millisecond,
+	}
+}
+
+// JobQueue 持久化打印队列
+// 提交时向队列文件追加含打印数据的完整记录，之后每次状态变化只追加不含打印数据的状态记录，
+// 启动时读取文件按任务 ID 合并记录恢复任务，
+// 未完成的任务（包括中断时正在打印的）重新排队，因此服务重启时任务可能重复打印但不会丢失
+type JobQueue struct {
+	path  string
+	retry RetryPolicy
+
+	mu      sync.Mutex
+	file    *os.File
+	writes  int   // 上次压缩后追加的记录数
+	written int64 // 上次压缩后追加的字节数
+	size    int64 // 上次压缩后的文件大小
+	jobs    map[string]*Job
+	pending map[string][]*Job        // 各打印机等待中的任务，按提交顺序
+	wake    map[string]chan struct{} // 通知打印机的工作协程有新任务
+	changed chan struct{}            // 任务状态变化时关闭并替换，用于等待任务结束
+
+	done    chan struct{} // 关闭时工作协程退出
+	workers sync.WaitGroup
+}
+
+// 打印队列，由 main 初始化
+var jobs *JobQueue
+
+// 任务不存在
+var errJobNotFound = errors.New("任务不存在")
+
+// 打开队列文件并恢复任务，压缩文件只保留每个任务的最新状态
+func openJobQueue(path string) (*JobQueue, error) {
+	q := &JobQueue{
+		path:    path,
+		jobs:    map[string]*Job{},
+		pending: map[string][]*Job{},
+		wake:    map[string]chan struct{}{},
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if file, err := os.Open(path); err == nil {
+		reader := bufio.NewReader(file)
+		line := 0
+		for {
+			data, err := readJobLine(reader)
+			if err == io.EOF {
+				break
+			}
+			line++
+			if err == errJobLineTooLong {
+				log.Printf("队列文件 %s 第%d行超过%d字节，已忽略", path, line, maxJobLine)
+				continue
+			}
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("读取队列文件失败: %v", err)
+			}
+			job := &Job{}
+			if err := json.Unmarshal(data, job); err != nil {
+				// 最后一行可能在写入时中断
+				log.Printf("队列文件 %s 第%d行无效，已忽略: %v", path, line, err)
+				continue
+			}
+			// 状态记录不含打印数据，沿用提交时的记录
+			if prev, ok := q.jobs[job.ID]; ok && job.Data == nil {
+				job.Data = prev.Data
+			}
+			q.jobs[job.ID] = job
+		}
+		file.Close()
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取队列文件失败: %v", err)
+	}
+
+	// 恢复未完成的任务
+	var restored []*Job
+	for _, job := range q.jobs {
+		switch job.Status {
+		case JobQueued, JobPrinting, JobRetrying:
+			if job.Data == nil {
+				// 提交时的记录已丢失（如超长被忽略）
+				now := time.Now()
+				job.Status, job.Error, job.FinishedAt, job.NextRetryAt = JobFailed, "队列文件中缺少打印数据", &now, nil
+				log.Printf("打印任务 %s 缺少打印数据，无法恢复", job.ID)
+				continue
+			}
+			job.Status, job.StartedAt, job.NextRetryAt = JobQueued, nil, nil
+			restored = append(restored, job)
+		case JobDone, JobCancelled:
+			job.Data = nil
+		}
+	}
+	sort.Slice(restored, func(i, j int) bool { return restored[i].CreatedAt.Before(restored[j].CreatedAt) })
+	for _, job := range restored {
+		q.pending[job.Printer] = append(q.pending[job.Printer], job)
+	}
+
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+	if len(restored) > 0 {
+		log.Printf("已恢复%d个未完成的打印任务", len(restored))
+	}
+	return q, nil
+}
+
+// 队列文件中的行超过 maxJobLine
+var errJobLineTooLong = errors.New("队列文件中的行过长")
+
+// 读取队列文件的一行，超过 maxJobLine 的行读到行尾后返回 errJobLineTooLong
+func readJobLine(r *bufio.Reader) ([]byte, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			if err == io.EOF && (len(line) > 0 || tooLong) {
+				break
+			}
+			return nil, err
+		}
+		if !tooLong && len(line)+len(chunk) > maxJobLine {
+			tooLong, line = true, nil
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if !isPrefix {
+			break
+		}
+	}
+	if tooLong {
+		return nil, errJobLineTooLong
+	}
+	return line, nil
+}
+
+// 清理过期任务并重写队列文件，每个任务一行
+func (q *JobQueue) compact() error {
+	now := time.Now()
+	for id, job := range q.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > jobRetention {
+			delete(q.jobs, id)
+		}
+	}
+
+	tmp := q.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("写入队列文件失败: %v", err)
+	}
+	all := make([]*Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		all = append(all, job)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].CreatedAt.Before(all[j].CreatedAt) })
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, job := range all {
+		enc.Encode(job)
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("写入队列文件失败: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("写入队列文件失败: %v", err)
+	}
+	info, err := file.Stat()
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("写入队列文件失败: %v", err)
+	}
+
+	// 替换前关闭旧文件（Windows 不能替换打开中的文件），替换失败时继续追加到旧文件
+	if q.file != nil {
+		q.file.Close()
+	}
+	renameErr := os.Rename(tmp, q.path)
+	q.file, err = os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("打开队列文件失败: %v", err)
+	}
+	if renameErr != nil {
+		return fmt.Errorf("写入队列文件失败: %v", renameErr)
+	}
+	q.writes, q.written, q.size = 0, 0, info.Size()
+	return nil
+}
+
+// 追加任务的当前状态，不含打印数据（打印数据只在提交和压缩时写入），调用时持有 q.mu
+// 写入失败时已记录日志，内存中的状态仍然生效，服务重启后恢复为最后写入成功的状态
+func (q *JobQueue) save(job *Job) {
+	q.write(job.view())
+}
+
+// 向队列文件追加一条记录，调用时持有 q.mu
+// 无论写入是否成功都通知等待任务状态的调用方，写入失败时记录日志并返回错误
+func (q *JobQueue) write(record *Job) error {
+	data, err := json.Marshal(record)
+	if err == nil {
+		if _, err = q.file.Write(append(data, '\n')); err == nil {
+			err = q.file.Sync()
+		}
+	}
+	close(q.changed)
+	q.changed = make(chan struct{})
+	if err != nil {
+		log.Printf("写入队列文件失败: %v", err)
+		return fmt.Errorf("写入队列文件失败: %v", err)
+	}
+
+	// 追加的记录过多或过大时压缩，过大按压缩后的文件大小比较，避免保留的打印数据较多时频繁压缩
+	q.writes++
+	q.written += int64(len(data) + 1)
+	if q.writes >= jobCompactWrites || q.written > max(jobCompactBytes, q.size) {
+		if err := q.compact(); err != nil {
+			// 压缩失败时继续追加，达到下一个阈值再尝试
+			q.writes, q.written = 0, 0
+			log.Printf("压缩队列文件失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// 为每台打印机启动工作协程
+func (q *JobQueue) start(names []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, name := range names {
+		wake := make(chan struct{}, 1)
+		q.wake[name] = wake
+		q.workers.Add(1)
+		go q.worker(name, wake)
+	}
+
+	// 配置中已删除的打印机上的任务无法打印
+	for name, pending := range q.pending {
+		if _, ok := q.wake[name]; ok {
+			continue
+		}
+		for _, job := range pending {
+			q.finish(job, fmt.Errorf("打印机不存在: %s", name), false)
+		}
+		delete(q.pending, name)
+	}
+}
+
+// Close 停止工作协程并关闭队列文件，正在打印的任务打印完成后返回
+func (q *JobQueue) Close() error {
+	close(q.done)
+	q.workers.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.file.Close()
+}
+
+// 提交任务，写入队列文件后返回；打印数据超过 maxJobData 时返回 JobSizeError
+func (q *JobQueue) Submit(printer, summary string, data []byte) (*Job, error) {
+	if len(data) > maxJobData {
+		return nil, &JobSizeError{Size: len(data), Limit: maxJobData}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job := &Job{
+		ID:        newJobID(),
+		Printer:   printer,
+		Status:    JobQueued,
+		Summary:   summary,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+	if err := q.write(job); err != nil {
+		return nil, err
+	}
+	q.jobs[job.ID] = job
+	q.pending[printer] = append(q.pending[printer], job)
+	q.notify(printer)
+	return job.view(), nil
+}
+
+// Cancel 取消等待打印或等待重试的任务
+func (q *JobQueue) Cancel(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errJobNotFound, id)
+	}
+	if job.Status != JobQueued && job.Status != JobRetrying {
+		return nil, fmt.Errorf("任务状态为 %s，只能取消等待中（queued、retrying）的任务", job.Status)
+	}
+	q.cancel(job)
+	return job.view(), nil
+}
+
+// 从队列中移除并取消任务，调用时持有 q.mu
+func (q *JobQueue) cancel(job *Job) {
+	pending := q.pending[job.Printer]
+	for i, j := range pending {
+		if j == job {
+			q.pending[job.Printer] = append(pending[:i:i], pending[i+1:]...)
+			break
+		}
+	}
+	now := time.Now()
+	job.Status, job.FinishedAt, job.NextRetryAt, job.Data = JobCancelled, &now, nil, nil
+	q.save(job)
+	// 取消的可能是正在等待重试的队首任务，唤醒工作协程继续打印后续任务
+	q.notify(job.Printer)
+}
+
+// Wait 等待任务结束
+// timeout 内任务仍未开始打印（前面的任务未完成或等待重试）时取消任务并返回 BusyError；
+// 已开始打印的任务等待打印完成，之后再次进入等待重试时同样取消
+func (q *JobQueue) Wait(id string, timeout time.Duration) (*Job, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	expired := false
+	for {
+		q.mu.Lock()
+		job, ok := q.jobs[id]
+		if !ok {
+			q.mu.Unlock()
+			return nil, fmt.Errorf("%w: %s", errJobNotFound, id)
+		}
+		switch job.Status {
+		case JobDone, JobFailed, JobCancelled:
+			q.mu.Unlock()
+			return job.view(), nil
+		case JobQueued, JobRetrying:
+			if expired {
+				busy := &BusyError{Printer: job.Printer, Timeout: int(timeout / time.Millisecond), JobID: job.ID, LastError: job.Error}
+				q.cancel(job)
+				q.mu.Unlock()
+				return nil, busy
+			}
+		}
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			expired = true
+		}
+	}
+}
+
+// Pending 打印机等待打印的任务数
+func (q *JobQueue) Pending(printer string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.pending[printer])
+}
+
+// Resubmit 将失败的任务重新加入队尾，重试次数重新计算
+func (q *JobQueue) Resubmit(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errJobNotFound, id)
+	}
+	if job.Status != JobFailed {
+		return nil, fmt.Errorf("任务状态为 %s，只能重新提交失败的任务", job.Status)
+	}
+	if _, ok := q.wake[job.Printer]; !ok {
+		return nil, fmt.Errorf("打印机不存在: %s", job.Printer)
+	}
+
+	job.Status, job.Attempts, job.Error, job.Partial = JobQueued, 0, "", false
+	job.StartedAt, job.FinishedAt = nil, nil
+	q.pending[job.Printer] = append(q.pending[job.Printer], job)
+	q.save(job)
+	q.notify(job.Printer)
+	return job.view(), nil
+}
+
+// List 按提交时间倒序列出任务，status、printer 为空时不过滤
+func (q *JobQueue) List(status, printer string) []*Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	list := []*Job{}
+	for _, job := range q.jobs {
+		if (status == "" || job.Status == status) && (printer == "" || job.Printer == printer) {
+			list = append(list, job.view())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// 唤醒打印机的工作协程，调用时持有 q.mu
+func (q *JobQueue) notify(printer string) {
+	select {
+	case q.wake[printer] <- struct{}{}:
+	default:
+	}
+}
+
+// Get 查询任务
+func (q *JobQueue) Get(id string) (*Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	return job.view(), true
+}
+
+// 任务副本，不含打印数据
+func (j *Job) view() *Job {
+	v := *j
+	v.Data = nil
+	return &v
+}
+
+// 打印机工作协程：依次打印该打印机的任务
+// 任务等待重试时，该打印机的后续任务也一起等待，保证打印顺序
+func (q *JobQueue) worker(name string, wake chan struct{}) {
+	defer q.workers.Done()
+	for {
+		select {
+		case <-q.done:
+			return
+		default:
+		}
+
+		job, wait := q.next(name)
+		if job == nil {
+			if wait == 0 {
+				select {
+				case <-wake:
+				case <-q.done:
+					return
+				}
+				continue
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-wake:
+			case <-timer.C:
+			case <-q.done:
+			}
+			timer.Stop()
+			continue
+		}
+
+		p, err := getPrinter(name)
+		retry := false
+		if err == nil {
+			// 端口被占用、网络中断、设备拔出等暂时性的传输错误可以重试；
+			// 已发送部分数据时不重试，重新发送整个任务会使打印机重复打印已收到的部分
+			err = sendToPrinter(p, job.Data)
+			var partial *partialWriteError
+			retry = err != nil && retryable(err) && !errors.As(err, &partial)
+		}
+		q.mu.Lock()
+		q.finish(job, err, retry)
+		q.mu.Unlock()
+	}
+}
+
+// 取出下一个任务并标记为正在打印，队首任务还未到重试时间时返回需要等待的时间
+func (q *JobQueue) next(printer string) (*Job, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending := q.pending[printer]
+	if len(pending) == 0 {
+		return nil, 0
+	}
+	job := pending[0]
+	if job.NextRetryAt != nil {
+		if wait := time.Until(*job.NextRetryAt); wait > 0 {
+			return nil, wait
+		}
+	}
+	q.pending[printer] = pending[1:]
+
+	now := time.Now()
+	job.Status, job.StartedAt, job.NextRetryAt, job.Partial = JobPrinting, &now, nil, false
+	job.Attempts++
+	q.save(job)
+	return job, 0
+}
+
+// 记录任务结果，可重试的错误在重试次数用尽前放回队首等待重试，调用时持有 q.mu
+func (q *JobQueue) finish(job *Job, err error, retry bool) {
+	var partial *partialWriteError
+	job.Partial = errors.As(err, &partial)
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		job.Status, job.Error, job.FinishedAt, job.Data = JobDone, "", &now, nil
+	case retry && job.Attempts <= q.retry.MaxRetries:
+		delay := q.retry.backoff(job.Attempts)
+		at := now.Add(delay)
+		job.Status, job.Error, job.NextRetryAt = JobRetrying, err.Error(), &at
+		q.pending[job.Printer] = append([]*Job{job}, q.pending[job.Printer]...)
+		log.Printf("打印任务 %s 第%d次打印失败，%v后重试: %v", job.ID, job.Attempts, delay, err)
+	default:
+		job.Status, job.Error, job.FinishedAt = JobFailed, err.Error(), &now
+		log.Printf("打印任务 %s 失败: %v", job.ID, err)
+	}
+	q.save(job)
+}
+
+// 生成任务 ID：时间戳加随机数，便于按时间排序
+func newJobID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return time.Now().Format("20060102150405") + "-" + hex.EncodeToString(b)
+}
+
+// 任务接口处理器
+//
+//	GET  /api/jobs?status=failed&printer=  任务列表，status=failed 即死信列表
+//	GET  /api/jobs/{id}                    查询任务
+//	POST /api/jobs/{id}/cancel             取消等待中的任务
+//	POST /api/jobs/{id}/retry              重新提交失败的任务
+func jobHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w)
+
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/jobs"), "/")
+	id, action, _ := strings.Cut(path, "/")
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch action {
+	case "":
+		if r.Method != "GET" {
+			sendError(w, "仅支持GET请求")
+			return
+		}
+		if id == "" {
+			query := r.URL.Query()
+			result = map[string][]*Job{"jobs": jobs.List(query.Get("status"), query.Get("printer"))}
+		} else if job, ok := jobs.Get(id); ok {
+			result = job
+		} else {
+			err = fmt.Errorf("%w: %s", errJobNotFound, id)
+		}
+
+	case "cancel", "retry":
+		if r.Method != "POST" {
+			sendError(w, "仅支持POST请求")
+			return
+		}
+		if action == "cancel" {
+			result, err = jobs.Cancel(id)
+		} else {
+			result, err = jobs.Resubmit(id)
+		}
+
+	default:
+		err = fmt.Errorf("%w: %s", errJobNotFound, path)
+	}
+
+	if errors.Is(err, errJobNotFound) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(PrintResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		sendError(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// 记录写入内容的传输通道
+type memTransport struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (t *memTransport) Open() error  { return nil }
+func (t *memTransport) Close() error { return nil }
+func (t *memTransport) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.buf.Write(p)
+}
+func (t *memTransport) Status() TransportStatus { return TransportStatus{Type: "memory"} }
+
+// 重启后未完成的任务按提交顺序重新打印，已完成的任务不再保存打印数据
+func TestJobQueueRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+
+	q, err := openJobQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := q.Submit("mem", "A", []byte("first\n"))
+	b, _ := q.Submit("mem", "B", []byte("second\n"))
+	q.next("mem") // 模拟打印 A 时服务中断
+	q.file.Close()
+
+	q, err = openJobQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{a.ID, b.ID} {
+		if job, ok := q.Get(id); !ok || job.Status != JobQueued || job.StartedAt != nil {
+			t.Fatalf("任务 %s 应重新排队: %+v", id, job)
+		}
+	}
+
+	mem := &memTransport{}
+	printers["mem"] = &Printer{Name: "mem", Transport: mem}
+	defer delete(printers, "mem")
+	q.start([]string{"mem"})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, _ := q.Get(b.ID)
+		if job.Status == JobDone {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("任务未完成: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := mem.buf.String(); got != "first\nsecond\n" {
+		t.Errorf("打印内容为 %q，应按提交顺序打印", got)
+	}
+
+	q.Close()
+	q, err = openJobQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.file.Close()
+	for _, id := range []string{a.ID, b.ID} {
+		job := q.jobs[id]
+		if job == nil || job.Status != JobDone || job.Data != nil {
+			t.Errorf("任务 %s 应为已完成且不含打印数据: %+v", id, job)
+		}
+	}
+}
+
+// 超过上限的任务不能提交，队列文件中过长的行在恢复时被忽略
+func TestJobQueueOversized(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+
+	q, err := openJobQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var size *JobSizeError
+	if _, err := q.Submit("mem", "big", make([]byte, maxJobData+1)); !errors.As(err, &size) {
+		t.Errorf("超过上限的任务应返回 JobSizeError: %v", err)
+	}
+	a, _ := q.Submit("mem", "A", []byte("a"))
+	q.file.Close()
+
+	// 在有效记录前插入一行超长记录
+	valid, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := append(bytes.Repeat([]byte("x"), maxJobLine+1), '\n')
+	if err := os.WriteFile(path, append(long, valid...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err = openJobQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.file.Close()
+	if job, ok := q.Get(a.ID); !ok || job.Status != JobQueued {
+		t.Errorf("超长行之后的任务应恢复: %+v", job)
+	}
+}
+
+// 打印数据只在提交时写入，状态变化追加不含打印数据的记录；追加的数据过大时压缩
+func TestJobQueueRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+	q, err := openJobQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("label\n"), 100)
+	a, _ := q.Submit("mem", "A", data)
+	job, _ := q.next("mem")
+	q.mu.Lock()
+	q.finish(job, errors.New("纸张用尽"), false)
+	q.mu.Unlock()
+	q.file.Close()
+
+	lines, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := strings.Split(strings.TrimSpace(string(lines)), "\n")
+	if len(records) != 3 || strings.Count(string(lines), `"data"`) != 1 || !strings.Contains(records[0], `"data"`) {
+		t.Fatalf("队列文件应为1条含打印数据的提交记录和2条状态记录:\n%s", lines)
+	}
+
+	// 重启后失败的任务沿用提交时的打印数据，可以重新提交
+	if q, err = openJobQueue(path); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { q.file.Close() }()
+	if job := q.jobs[a.ID]; job.Status != JobFailed || !bytes.Equal(job.Data, data) {
+		t.Fatalf("重启后的任务为 %s，打印数据 %d 字节", job.Status, len(job.Data))
+	}
+
+	// 追加的数据超过上限时压缩，每个任务只保留一行
+	b, _ := q.Submit("mem", "B", []byte("b"))
+	q.mu.Lock()
+	q.written = jobCompactBytes
+	q.cancel(q.jobs[b.ID])
+	q.mu.Unlock()
+	lines, _ = os.ReadFile(path)
+	if n := strings.Count(string(lines), "\n"); n != 2 || q.written != 0 {
+		t.Errorf("压缩后队列文件有 %d 行，应为 2 行:\n%s", n, lines)
+	}
+}
+
+// 前 fails 次打开失败的传输通道，err 为空时返回端口被占用
+type flakyTransport struct {
+	memTransport
+	fails int
+	err   error
+}
+
+func (t *flakyTransport) Open() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fails > 0 {
+		t.fails--
+		if t.err != nil {
+			return t.err
+		}
+		return &os.PathError{Op: "open", Path: "/dev/usb/lp0", Err: syscall.EBUSY}
+	}
+	return nil
+}
+
+// 等待任务变为指定状态
+func waitJob(t *testing.T, q *JobQueue, id, status string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, _ := q.Get(id)
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("任务状态为 %s，应为 %s: %+v", job.Status, status, job)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 传输错误按次数重试，用尽后进入死信列表，可重新提交；等待重试的任务可以取消
+func TestJobQueueRetry(t *testing.T) {
+	q, err := openJobQueue(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	q.retry = RetryPolicy{MaxRetries: 2, Delay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+	flaky := &flakyTransport{fails: 5}
+	printers["flaky"] = &Printer{Name: "flaky", Transport: flaky}
+	defer delete(printers, "flaky")
+	q.start([]string{"flaky"})
+
+	// 1 次打印加 2 次重试后失败
+	a, _ := q.Submit("flaky", "A", []byte("a"))
+	job := waitJob(t, q, a.ID, JobFailed)
+	if job.Attempts != 3 || job.Error == "" {
+		t.Errorf("失败任务应尝试3次并记录错误: %+v", job)
+	}
+	if list := q.List(JobFailed, ""); len(list) != 1 || list[0].ID != a.ID {
+		t.Errorf("死信列表应只包含任务 %s: %+v", a.ID, list)
+	}
+
+	// 剩余 2 次失败后第 3 次成功
+	if _, err := q.Resubmit(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if job := waitJob(t, q, a.ID, JobDone); job.Attempts != 3 {
+		t.Errorf("重新提交的任务应在第3次成功: %+v", job)
+	}
+	if _, err := q.Resubmit(a.ID); err == nil {
+		t.Error("已完成的任务不能重新提交")
+	}
+
+	// 等待重试时取消，后续任务继续打印
+	q.mu.Lock()
+	q.retry.Delay, q.retry.MaxDelay = time.Hour, time.Hour
+	q.mu.Unlock()
+	flaky.mu.Lock()
+	flaky.fails = 1
+	flaky.mu.Unlock()
+	b, _ := q.Submit("flaky", "B", []byte("b"))
+	c, _ := q.Submit("flaky", "C", []byte("c"))
+	waitJob(t, q, b.ID, JobRetrying)
+	if _, err := q.Cancel(b.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitJob(t, q, c.ID, JobDone)
+	if job, _ := q.Get(b.ID); job.Status != JobCancelled {
+		t.Errorf("任务应已取消: %+v", job)
+	}
+	if _, err := q.Cancel(c.ID); err == nil {
+		t.Error("已完成的任务不能取消")
+	}
+	if _, err := q.Cancel("missing"); !errors.Is(err, errJobNotFound) {
+		t.Errorf("取消不存在的任务应返回 errJobNotFound: %v", err)
+	}
+
+	flaky.mu.Lock()
+	defer flaky.mu.Unlock()
+	if got := flaky.buf.String(); got != "ac" {
+		t.Errorf("打印内容为 %q，应为 \"ac\"", got)
+	}
+}
+
+// 写入 fails 次时只写入一半数据后返回连接断开的传输通道
+type partialTransport struct {
+	memTransport
+	fails int
+}
+
+func (t *partialTransport) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fails > 0 {
+		t.fails--
+		n, _ := t.buf.Write(p[:len(p)/2])
+		return n, syscall.EPIPE
+	}
+	return t.buf.Write(p)
+}
+
+// 已发送部分数据后写入失败时不自动重试，任务记录 partial，重新提交后再次打印
+func TestJobQueuePartialWrite(t *testing.T) {
+	q, err := openJobQueue(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	q.retry = RetryPolicy{MaxRetries: 2, Delay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+	partial := &partialTransport{fails: 1}
+	printers["partial"] = &Printer{Name: "partial", Transport: partial}
+	defer delete(printers, "partial")
+	q.start([]string{"partial"})
+
+	a, _ := q.Submit("partial", "A", []byte("abcd"))
+	job := waitJob(t, q, a.ID, JobFailed)
+	if job.Attempts != 1 || !job.Partial || !strings.Contains(job.Error, "已发送2/4字节") {
+		t.Errorf("部分写入后应直接失败并记录 partial: %+v", job)
+	}
+
+	if _, err := q.Resubmit(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if job := waitJob(t, q, a.ID, JobDone); job.Partial {
+		t.Errorf("重新打印成功后不应记录 partial: %+v", job)
+	}
+	partial.mu.Lock()
+	defer partial.mu.Unlock()
+	if got := partial.buf.String(); got != "ababcd" {
+		t.Errorf("打印内容为 %q，应为部分写入的 \"ab\" 加重新提交的 \"abcd\"", got)
+	}
+}
+
+// 关闭时返回错误的传输通道，模拟虚拟打印机保存图片失败
+type closeFailTransport struct {
+	memTransport
+}
+
+func (t *closeFailTransport) Close() error { return syscall.EIO }
+
+// 数据写入后关闭失败时任务失败，数据已全部发送，不自动重试
+func TestJobQueueCloseError(t *testing.T) {
+	q, err := openJobQueue(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	q.retry = RetryPolicy{MaxRetries: 2, Delay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+	printers["closefail"] = &Printer{Name: "closefail", Transport: &closeFailTransport{}}
+	defer delete(printers, "closefail")
+	q.start([]string{"closefail"})
+
+	a, _ := q.Submit("closefail", "A", []byte("abcd"))
+	job := waitJob(t, q, a.ID, JobFailed)
+	if job.Attempts != 1 || !job.Partial || !strings.Contains(job.Error, "关闭打印机失败") {
+		t.Errorf("关闭失败应直接失败并记录 partial: %+v", job)
+	}
+}
+
+// 权限、配置等不可重试的错误直接失败
+func TestJobQueueNoRetry(t *testing.T) {
+	q, err := openJobQueue(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	q.retry = RetryPolicy{MaxRetries: 2, Delay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+	denied := describeDeviceError("/dev/usb/lp0", &os.PathError{Op: "open", Path: "/dev/usb/lp0", Err: syscall.EACCES})
+	flaky := &flakyTransport{fails: 1, err: denied}
+	printers["denied"] = &Printer{Name: "denied", Transport: flaky}
+	defer delete(printers, "denied")
+	q.start([]string{"denied"})
+
+	a, _ := q.Submit("denied", "A", []byte("a"))
+	if job := waitJob(t, q, a.ID, JobFailed); job.Attempts != 1 || !strings.Contains(job.Error, "没有权限") {
+		t.Errorf("权限错误应在第1次打印后直接失败: %+v", job)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	path := "/dev/usb/lp0"
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{describeDeviceError(path, &os.PathError{Op: "open", Path: path, Err: syscall.ENOENT}), true},
+		{describeDeviceError(path, &os.PathError{Op: "open", Path: path, Err: syscall.EACCES}), false},
+		{describeDeviceError(path, &os.PathError{Op: "open", Path: path, Err: syscall.EBUSY}), true},
+		{&transportError{"设备 /dev/usb/lp0 正被其他任务占用", syscall.EBUSY}, true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}, true},
+		{&net.OpError{Op: "write", Net: "tcp", Err: &os.SyscallError{Syscall: "write", Err: syscall.ECONNRESET}}, true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "printer.local", IsNotFound: true}}, false},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: &net.AddrError{Err: "too many colons in address", Addr: "::1:2:9100"}}, false},
+		{&net.OpError{Op: "write", Net: "tcp", Err: os.ErrDeadlineExceeded}, true},
+		{errors.New("不支持的波特率: 12345"), false},
+	}
+	for _, tc := range cases {
+		err := fmt.Errorf("无法打开打印机端口: %w", tc.err)
+		if got := retryable(err); got != tc.want {
+			t.Errorf("retryable(%v) = %t，应为 %t", err, got, tc.want)
+		}
+	}
+}
+
+// 打开后阻塞到 release 关闭的传输通道
+type blockingTransport struct {
+	memTransport
+	release chan struct{}
+}
+
+func (t *blockingTransport) Open() error {
+	<-t.release
+	return nil
+}
+
+// 同步等待：超时仍未开始打印的任务被取消并返回 BusyError，已开始打印的任务等待完成
+func TestJobQueueWait(t *testing.T) {
+	q, err := openJobQueue(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	blocking := &blockingTransport{release: make(chan struct{})}
+	printers["blocking"] = &Printer{Name: "blocking", Transport: blocking}
+	defer delete(printers, "blocking")
+	q.start([]string{"blocking"})
+
+	a, _ := q.Submit("blocking", "A", []byte("a"))
+	b, _ := q.Submit("blocking", "B", []byte("b"))
+	waitJob(t, q, a.ID, JobPrinting)
+
+	var busy *BusyError
+	if _, err := q.Wait(b.ID, 20*time.Millisecond); !errors.As(err, &busy) || busy.JobID != b.ID {
+		t.Fatalf("打印机忙时应返回 BusyError: %v", err)
+	}
+	if job, _ := q.Get(b.ID); job.Status != JobCancelled {
+		t.Errorf("等待超时的任务应已取消: %+v", job)
+	}
+
+	// 正在打印的任务超时后继续等待
+	time.AfterFunc(50*time.Millisecond, func() { close(blocking.release) })
+	job, err := q.Wait(a.ID, 10*time.Millisecond)
+	if err != nil || job.Status != JobDone {
+		t.Errorf("正在打印的任务应等待完成: %+v, %v", job, err)
+	}
+	if q.Pending("blocking") != 0 {
+		t.Errorf("队列中不应有等待的任务")
+	}
+}
+
+// 写入队列文件失败时仍通知等待的调用方
+func TestJobQueueSaveError(t *testing.T) {
+	q, err := openJobQueue(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	a, _ := q.Submit("mem", "A", []byte("a"))
+	q.file.Close()
+
+	done := make(chan *Job)
+	go func() {
+		job, _ := q.Wait(a.ID, 5*time.Second)
+		done <- job
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if _, err := q.Cancel(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case job := <-done:
+		if job == nil || job.Status != JobCancelled {
+			t.Errorf("等待结果为 %+v，应为已取消", job)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("写入队列文件失败后等待的调用方没有收到通知")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// 当前平台不支持 flock，设备独占由操作系统保证
+func lockDevice(file *os.File, timeout time.Duration) error {
+	return nil
+}
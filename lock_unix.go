@@ -0,0 +1,29 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// 对设备文件加排他锁（flock），超时未获得则返回错误
+func lockDevice(file *os.File, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return fmt.Errorf("锁定设备 %s 失败: %w", file.Name(), err)
+		}
+		if time.Now().After(deadline) {
+			return &transportError{fmt.Sprintf("设备 %s 正被其他任务占用", file.Name()), syscall.EBUSY}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xiaofeiwuuu/tiaoxingma/barcode"
+	"github.com/xiaofeiwuuu/tiaoxingma/escpos"
+)
+
+// 容量校验与编码器一致：校验通过的数据都能编码，超出容量的返回 CapacityError
+func TestCheckPDF417(t *testing.T) {
+	for _, data := range []string{"1", "ABC", strings.Repeat("9", 47), strings.Repeat("Ab", 60), strings.Repeat("\xff", 70)} {
+		for _, size := range [][2]int{{0, 0}, {1, 0}, {0, 3}, {2, 10}, {1, 3}} {
+			for _, level := range []int{0, 1, 4} {
+				req := &PrintRequest{BarcodeData: data, PDF417Columns: size[0], PDF417Rows: size[1], PDF417ErrorLevel: level, PDF417ModuleWidth: 3, PDF417RowHeight: 3}
+				got, err := checkPDF417(req)
+				if err != nil {
+					if _, ok := err.(*CapacityError); !ok {
+						t.Fatalf("%q %v 等级 %d: %v", data, size, level, err)
+					}
+				}
+				want := level
+				if want == 0 {
+					want = barcode.PDF417AutoLevel(data)
+				}
+				_, encodeErr := barcode.EncodePDF417(data, size[0], size[1], want, 3)
+				if (err == nil) != (encodeErr == nil) || err == nil && got != want {
+					t.Errorf("%q %v 等级 %d: 校验结果 %d, %v，编码结果 %v", data, size, level, got, err, encodeErr)
+				}
+			}
+		}
+	}
+}
+
+// 虚拟打印机解释 GS ( k 指令得到的 PDF417 与光栅模式的符号一致（不含静区）
+func TestFirmwarePDF417Render(t *testing.T) {
+	req := PrintRequest{BarcodeType: "PDF417", BarcodeData: "PDF417 firmware 1234567890123", PDF417Columns: 3, PDF417ModuleWidth: 2, PDF417RowHeight: 4}
+	if err := prepareRequest(&req); err != nil {
+		t.Fatal(err)
+	}
+	cmds, err := buildCommands(firmwarePrinter, &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := escpos.Render(cmds, 576, 203)
+	if len(r.Warnings) > 0 {
+		t.Fatal(r.Warnings)
+	}
+
+	level, _ := checkPDF417(&req)
+	symbol, err := barcode.EncodePDF417(req.BarcodeData, req.PDF417Columns, req.PDF417Rows, level, req.PDF417RowHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := symbol.Scale(req.PDF417ModuleWidth, 0)
+
+	// 图片中黑色像素的范围即为符号
+	left, top := r.Image.Width, r.Image.Height
+	right, bottom := -1, -1
+	for y := 0; y < r.Image.Height; y++ {
+		for x := 0; x < r.Image.Width; x++ {
+			if r.Image.Get(x, y) {
+				left, top = min(left, x), min(top, y)
+				right, bottom = max(right, x), max(bottom, y)
+			}
+		}
+	}
+	if right-left+1 != want.Width || bottom-top+1 != want.Height {
+		t.Fatalf("打印的 PDF417 为 %dx%d 点，应为 %dx%d 点", right-left+1, bottom-top+1, want.Width, want.Height)
+	}
+	for y := 0; y < want.Height; y++ {
+		for x := 0; x < want.Width; x++ {
+			if r.Image.Get(left+x, top+y) != want.Get(x, y) {
+				t.Fatalf("打印的 PDF417 在 (%d,%d) 与光栅图不一致", x, y)
+			}
+		}
+	}
+}
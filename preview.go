@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xiaofeiwuuu/tiaoxingma/barcode"
+)
+
+// 预览处理器：按打印机的分辨率和纸宽渲染打印效果
+// GET 使用查询参数，POST 使用与 /api/print 相同的 JSON；
+// 输出格式由 format 参数（png、svg）或 Accept 头决定，默认 PNG
+func previewHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w)
+
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	format, err := previewFormat(r)
+	if err != nil {
+		sendError(w, err.Error())
+		return
+	}
+
+	var req PrintRequest
+	switch r.Method {
+	case "GET":
+		if err := requestFromQuery(r.URL.Query(), &req); err != nil {
+			sendError(w, err.Error())
+			return
+		}
+	case "POST":
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			sendError(w, "读取请求失败")
+			return
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			sendError(w, "解析JSON失败")
+			return
+		}
+	default:
+		sendError(w, "仅支持GET和POST请求")
+		return
+	}
+
+	if err := prepareRequest(&req); err != nil {
+		sendPrintError(w, err)
+		return
+	}
+	p, err := getPrinter(req.Printer)
+	if err != nil {
+		sendError(w, err.Error())
+		return
+	}
+	// 序列号预览第一个标签，不占用计数器
+	if seq := req.Sequence; seq != nil {
+		first := seq.Start
+		if seq.Counter != "" {
+			first = counters.Peek(seq.Counter, seq.Start)
+		}
+		req.BarcodeData = seq.value(first, 0)
+	}
+	if err := validateBarcode(&req); err != nil {
+		sendPrintError(w, err)
+		return
+	}
+
+	page, err := renderPreview(p, &req)
+	if err != nil {
+		sendPrintError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(encodeSVG(page))
+		return
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, page.Image()); err != nil {
+		sendError(w, "生成PNG失败")
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
+
+// 预览格式：format 参数优先，其次 Accept 头
+func previewFormat(r *http.Request) (string, error) {
+	if f := strings.ToLower(r.URL.Query().Get("format")); f != "" {
+		if f != "png" && f != "svg" {
+			return "", fmt.Errorf("不支持的预览格式: %s（可用 png、svg）", f)
+		}
+		return f, nil
+	}
+	if strings.Contains(r.Header.Get("Accept"), "image/svg+xml") {
+		return "svg", nil
+	}
+	return "png", nil
+}
+
+// 按 JSON 字段名将查询参数填入请求
+func requestFromQuery(values url.Values, req *PrintRequest) error {
+	v := reflect.ValueOf(req).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		raw := values.Get(name)
+		if raw == "" {
+			continue
+		}
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(raw)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("参数 %s 必须是 true 或 false", name)
+			}
+			field.SetBool(b)
+		case reflect.Int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("参数 %s 必须是整数", name)
+			}
+			field.SetInt(int64(n))
+		case reflect.Float64:
+			f, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return fmt.Errorf("参数 %s 必须是数字", name)
+			}
+			field.SetFloat(f)
+		}
+	}
+	return nil
+}
+
+// 渲染打印预览：上方空一行，条码按对齐方式放置，文字在条码下方，之后走纸三行
+func renderPreview(p *Printer, req *PrintRequest) (*barcode.Bitmap, error) {
+	barcodeType := strings.ToUpper(req.BarcodeType)
+	code, text, err := renderRaster(req)
+	if err != nil {
+		return nil, err
+	}
+
+	width := p.Config.printWidth()
+	if code.Width > width {
+		return nil, &WidthError{Width: code.Width, PrintWidth: width}
+	}
+
+	// 默认行距约 3.75mm，文字按 203dpi 放大 2 倍
+	dpi := p.Config.dpi()
+	lineHeight := int(3.75*float64(dpi)/25.4 + 0.5)
+	scale := (dpi + 50) / 100
+	if scale < 1 {
+		scale = 1
+	}
+
+	var lines []string
+	if req.ShowText && text != "" {
+		lines = wrapText(text, width/(barcode.FontWidth*scale))
+	}
+	textHeight := len(lines) * barcode.FontHeight * scale
+	if textHeight > 0 {
+		textHeight += scale * 2 // 条码与文字之间的间隙
+	}
+	feed := 3
+	if req.Cut {
+		feed += 4
+	}
+	page := barcode.NewBitmap(width, lineHeight+code.Height+textHeight+feed*lineHeight)
+
+	x := 0
+	if req.Center {
+		x = (width - code.Width) / 2
+	}
+	y := lineHeight
+	page.Draw(code, x, y)
+	y += code.Height + scale*2
+
+	// 固件打印的一维条码文字（HRI）居中于条码下方，其他情况按对齐方式打印
+	hriUnder := !useRaster(p, req) && barcodeType != "QR" && barcodeType != "PDF417" && barcodeType != "GS1128"
+	for _, line := range lines {
+		tw := barcode.TextWidth(line, scale)
+		tx := 0
+		switch {
+		case hriUnder:
+			tx = x + (code.Width-tw)/2
+		case req.Center:
+			tx = (width - tw) / 2
+		}
+		if tx < 0 {
+			tx = 0
+		}
+		page.DrawText(tx, y, line, scale)
+		y += barcode.FontHeight * scale
+	}
+
+	// 切纸位置以虚线表示
+	if req.Cut {
+		for xx := 0; xx < width; xx += 8 {
+			page.FillRect(xx, page.Height-1, 4, 1, true)
+		}
+	}
+	return page, nil
+}
+
+// 按每行最多 n 个字符折行
+func wrapText(text string, n int) []string {
+	runes := []rune(text)
+	if n < 1 {
+		n = 1
+	}
+	var lines []string
+	for len(runes) > n {
+		lines = append(lines, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return append(lines, string(runes))
+}
+
+// 位图转换为 SVG，每行的黑色区段合并为矩形，相同的连续行合并为一个矩形
+func encodeSVG(bm *barcode.Bitmap) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		bm.Width, bm.Height, bm.Width, bm.Height)
+	buf.WriteString(`<rect width="100%" height="100%" fill="#fff"/><path fill="#000" d="`)
+
+	runs := func(y int) [][2]int {
+		var r [][2]int
+		for x := 0; x < bm.Width; {
+			if !bm.Get(x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < bm.Width && bm.Get(x, y) {
+				x++
+			}
+			r = append(r, [2]int{start, x - start})
+		}
+		return r
+	}
+	for y := 0; y < bm.Height; {
+		row := runs(y)
+		h := 1
+		for y+h < bm.Height && reflect.DeepEqual(runs(y+h), row) {
+			h++
+		}
+		for _, r := range row {
+			fmt.Fprintf(&buf, "M%d %dh%dv%dh-%dz", r[0], y, r[1], h, r[1])
+		}
+		y += h
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
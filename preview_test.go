@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// PDF417 预览与光栅模式使用同一个编码器，符号位于第一行下方
+func TestPreviewPDF417(t *testing.T) {
+	req := PrintRequest{BarcodeType: "PDF417", BarcodeData: "PDF417 preview", PDF417ModuleWidth: 2, PDF417RowHeight: 3, Center: true}
+	page, err := renderPreview(firmwarePrinter, &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	symbol, _, err := renderRaster(&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	width := firmwarePrinter.Config.printWidth()
+	x := (width - symbol.Width) / 2
+	y := int(3.75*float64(firmwarePrinter.Config.dpi())/25.4 + 0.5)
+	for sy := 0; sy < symbol.Height; sy++ {
+		for sx := 0; sx < symbol.Width; sx++ {
+			if page.Get(x+sx, y+sy) != symbol.Get(sx, sy) {
+				t.Fatalf("预览中 (%d,%d) 与光栅图不一致", sx, sy)
+			}
+		}
+	}
+}
+
+// format 参数只接受 png 和 svg，其他格式返回 400
+func TestPreviewFormat(t *testing.T) {
+	for query, want := range map[string]string{"": "png", "format=SVG": "svg", "format=png": "png"} {
+		r := httptest.NewRequest("GET", "/api/preview?"+query, nil)
+		if got, err := previewFormat(r); err != nil || got != want {
+			t.Errorf("%q 的预览格式为 %q, %v，应为 %q", query, got, err, want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	previewHandler(w, httptest.NewRequest("GET", "/api/preview?barcodeData=A&format=jpg", nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "png、svg") {
+		t.Errorf("format=jpg 应返回 400 并列出支持的格式: %d %s", w.Code, w.Body.String())
+	}
+}
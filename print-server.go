@@ -1,15 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"runtime"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
+
+	"github.com/xiaofeiwuuu/tiaoxingma/barcode"
 )
 
 // PrintRequest 打印请求结构
@@ -21,22 +32,85 @@ type PrintRequest struct {
 	Center        bool   `json:"center"`        // 是否居中
 	BarcodeWidth  int    `json:"barcodeWidth"`  // 条形码宽度 (2-6)
 	BarcodeHeight int    `json:"barcodeHeight"` // 条形码高度 (1-255)
+	Printer       string `json:"printer"`       // 打印机名称，为空时使用默认打印机
+	RenderMode    string `json:"renderMode"`    // 渲染方式：firmware（打印机固件 GS k，默认）, raster（软件编码光栅图 GS v 0）
+	QRErrorLevel  string `json:"qrErrorLevel"`  // QR码纠错等级：L, M, Q, H（默认 M）
+	QRModuleSize  int    `json:"qrModuleSize"`  // QR码模块大小 (1-16)，默认 6
+	QRModel       int    `json:"qrModel"`       // QR码模型：1 或 2（默认 2）
+
+	PDF417Columns     int `json:"pdf417Columns"`     // PDF417数据列数 (1-30)，0 为自动
+	PDF417Rows        int `json:"pdf417Rows"`        // PDF417行数 (3-90)，0 为自动
+	PDF417ModuleWidth int `json:"pdf417ModuleWidth"` // PDF417模块宽度 (2-8)，默认 3
+	PDF417RowHeight   int `json:"pdf417RowHeight"`   // PDF417行高，模块宽度的倍数 (2-8)，默认 3
+	PDF417ErrorLevel  int `json:"pdf417ErrorLevel"`  // PDF417纠错等级 (1-8)，0 为按数据量自动选择
+
+	DataMatrixShape      string `json:"dataMatrixShape"`      // Data Matrix形状：square（默认）, rectangle, auto
+	DataMatrixModuleSize int    `json:"dataMatrixModuleSize"` // Data Matrix模块大小 (1-16)，默认 6
+
+	WideRatio float64 `json:"wideRatio"` // 光栅模式下 CODABAR 的宽窄比 (2-3)，默认 3
+
+	GS1Data map[string]string `json:"gs1Data"` // GS1-128 的 AI 数据，如 {"01": "09501101530003"}，设置后忽略 barcodeData
+	Addon   string            `json:"addon"`   // EAN-13/UPC 的 2 位或 5 位附加码，以光栅图打印
+
+	Copies   int           `json:"copies"`   // 打印份数，默认 1
+	Sequence *SequenceSpec `json:"sequence"` // 序列号，设置后按序列生成条码数据，不能同时设置 barcodeData
 }
 
 // PrintResponse 打印响应结构
 type PrintResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Code    string       `json:"code,omitempty"`   // 错误代码
+	Detail  interface{}  `json:"detail,omitempty"` // 错误详情
+	Size    *BarcodeSize `json:"size,omitempty"`   // 条码宽度（CODE128、GS1-128）
+	JobID   string       `json:"jobId,omitempty"`  // 打印任务 ID，通过 /api/jobs/{id} 查询状态
+
+	Sequence *SequenceResult   `json:"sequence,omitempty"` // 本次打印的序列号范围
+	Results  []BatchItemResult `json:"results,omitempty"`  // 批量打印各标签的结果
+}
+
+// BarcodeSize 条码宽度，便于调用方判断是否超出纸宽
+type BarcodeSize struct {
+	Modules int `json:"modules"` // 模块数，不含静区
+	Dots    int `json:"dots"`    // 打印点数（模块数 × 模块宽度）
 }
 
 func main() {
+	configPath := flag.String("config", "config.json", "配置文件路径")
+	flag.Parse()
+
+	// 加载配置
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := setupPrinters(cfg); err != nil {
+		log.Fatal(err)
+	}
+	templateDir = cfg.TemplateDir
+	if jobs, err = openJobQueue(cfg.JobFile); err != nil {
+		log.Fatal(err)
+	}
+	jobs.retry = cfg.retryPolicy()
+	jobs.start(printerNames)
+	if counters, err = openCounterStore(cfg.CounterFile); err != nil {
+		log.Fatal(err)
+	}
+
 	// 设置服务端口
-	port := ":9100"
-	
+	port := cfg.Listen
+
 	// 注册路由
 	http.HandleFunc("/", homeHandler)
 	http.HandleFunc("/api/print", printHandler)
+	http.HandleFunc("/api/print/template", templatePrintHandler)
+	http.HandleFunc("/api/print/batch", batchPrintHandler)
 	http.HandleFunc("/api/status", statusHandler)
+	http.HandleFunc("/api/jobs", jobHandler)
+	http.HandleFunc("/api/jobs/", jobHandler)
+	http.HandleFunc("/api/counters", counterHandler)
+	http.HandleFunc("/api/counters/", counterHandler)
+	http.HandleFunc("/api/preview", previewHandler)
 	http.HandleFunc("/test", testPageHandler)
 
 	// 启动服务
@@ -46,6 +120,15 @@ func main() {
 	fmt.Printf("服务地址: http://localhost%s\n", port)
 	fmt.Println("测试页面: http://localhost" + port + "/test")
 	fmt.Println("API接口: http://localhost" + port + "/api/print")
+	fmt.Println("打印预览: http://localhost" + port + "/api/preview")
+	fmt.Println("任务管理: http://localhost" + port + "/api/jobs（队列文件 " + cfg.JobFile + "）")
+	fmt.Println("模板打印: http://localhost" + port + "/api/print/template（模板目录 " + templateDir + "）")
+	fmt.Println("批量打印: http://localhost" + port + "/api/print/batch")
+	fmt.Println("序列号计数器: http://localhost" + port + "/api/counters（计数器文件 " + cfg.CounterFile + "）")
+	for _, name := range printerNames {
+		status := printers[name].Transport.Status()
+		fmt.Printf("打印机: %s (%s %s)\n", name, status.Type, status.Address)
+	}
 	fmt.Println("\n按 Ctrl+C 停止服务")
 	fmt.Println("=======================================")
 
@@ -54,8 +137,30 @@ func main() {
 		openBrowser("http://localhost" + port + "/test")
 	}()
 
+	// 收到 Ctrl+C 或 SIGTERM 时停止接收请求，等待正在打印的任务完成后关闭任务队列
+	server := &http.Server{Addr: port}
+	stopped := make(chan struct{})
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		fmt.Println("正在停止服务...")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("停止HTTP服务失败: %v", err)
+		}
+		close(stopped)
+	}()
+
 	// 启动HTTP服务
-	log.Fatal(http.ListenAndServe(port, nil))
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-stopped
+	if err := jobs.Close(); err != nil {
+		log.Printf("关闭任务队列失败: %v", err)
+	}
 }
 
 // 主页处理器
@@ -81,13 +186,23 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
 // 状态检查处理器
 func statusHandler(w http.ResponseWriter, r *http.Request) {
 	enableCORS(w)
-	
+
+	// 收集各打印机状态
+	statuses := map[string]TransportStatus{}
+	for _, name := range printerNames {
+		status := printers[name].Transport.Status()
+		status.Pending = jobs.Pending(name)
+		statuses[name] = status
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status": "running",
-		"version": "3.0.0",
-		"port": "LPT1",
-		"features": []string{"barcode"},
+		"status":         "running",
+		"version":        "3.0.0",
+		"port":           statuses[defaultPrinter].Address,
+		"defaultPrinter": defaultPrinter,
+		"printers":       statuses,
+		"features":       []string{"barcode", "qrcode", "pdf417", "datamatrix", "preview", "template", "batch", "copies", "sequence", "sync", "jobs"},
 	})
 }
 
@@ -117,7 +232,28 @@ func printHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 设置默认值
+	if err := prepareRequest(&req); err != nil {
+		sendPrintError(w, err)
+		return
+	}
+
+	// 加入打印队列
+	job, seq, err := submitBarcode(&req)
+	if err != nil {
+		sendPrintError(w, err)
+		return
+	}
+
+	// 返回任务 ID，CODE128 附带条码宽度，序列号附带范围
+	resp := PrintResponse{Sequence: seq}
+	if seq == nil {
+		resp.Size = barcodeSize(&req)
+	}
+	sendJobResponse(w, r, job, resp)
+}
+
+// 设置请求默认值并检查通用参数
+func prepareRequest(req *PrintRequest) error {
 	if req.BarcodeWidth == 0 {
 		req.BarcodeWidth = 3
 	}
@@ -127,15 +263,102 @@ func printHandler(w http.ResponseWriter, r *http.Request) {
 	if req.BarcodeType == "" {
 		req.BarcodeType = "CODE128"
 	}
+	if req.QRModuleSize == 0 {
+		req.QRModuleSize = 6
+	}
+	if req.QRModel == 0 {
+		req.QRModel = 2
+	}
+	if req.WideRatio == 0 {
+		req.WideRatio = 3
+	}
+	if req.DataMatrixModuleSize == 0 {
+		req.DataMatrixModuleSize = 6
+	}
+	if req.PDF417ModuleWidth == 0 {
+		req.PDF417ModuleWidth = 3
+	}
+	if req.PDF417RowHeight == 0 {
+		req.PDF417RowHeight = 3
+	}
+	if req.Copies == 0 {
+		req.Copies = 1
+	}
+	// GS h 只有一个字节
+	if req.BarcodeHeight < 1 || req.BarcodeHeight > 255 {
+		return fmt.Errorf("barcodeHeight 必须是 1-255: %d", req.BarcodeHeight)
+	}
+	if req.Copies < 1 || req.Copies > maxBatchItems {
+		return fmt.Errorf("copies 必须是 1-%d: %d", maxBatchItems, req.Copies)
+	}
+	if req.Sequence != nil {
+		if req.BarcodeData != "" || len(req.GS1Data) > 0 {
+			return fmt.Errorf("设置 sequence 时不能设置 barcodeData 或 gs1Data")
+		}
+		if err := req.Sequence.check(); err != nil {
+			return err
+		}
+		if n := req.Sequence.Count * req.Copies; n > maxBatchItems {
+			return fmt.Errorf("序列号个数乘以份数最多%d个标签，当前%d个", maxBatchItems, n)
+		}
+	}
+	// GS1-128 的 AI 映射转换为 (AI)数据 格式
+	if strings.ToUpper(req.BarcodeType) == "GS1128" && len(req.GS1Data) > 0 {
+		elements, err := barcode.GS1FromMap(req.GS1Data)
+		if err != nil {
+			return &barcode.DataError{BarcodeType: "GS1128", Reason: err.Error()}
+		}
+		req.BarcodeData = barcode.GS1Text(elements)
+	}
+	req.RenderMode = strings.ToLower(req.RenderMode)
+	if req.RenderMode == "" {
+		req.RenderMode = "firmware"
+	}
+	if req.RenderMode != "firmware" && req.RenderMode != "raster" {
+		return fmt.Errorf("renderMode 必须是 firmware 或 raster")
+	}
+	// 附加码会使请求改用光栅模式，QR、Codabar 等类型的光栅编码不处理附加码，须在此拒绝
+	if req.Addon != "" {
+		if err := barcode.CheckAddonType(req.BarcodeType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// 执行打印
-	if err := printBarcode(&req); err != nil {
-		sendError(w, err.Error())
-		return
+// 计算 CODE128（含 A 子集）和 GS1-128 的条码宽度，其他类型返回 nil
+func barcodeSize(req *PrintRequest) *BarcodeSize {
+	var code *barcode.Code128
+	switch strings.ToUpper(req.BarcodeType) {
+	case "CODE128", "":
+		c, err := barcode.EncodeCode128(req.BarcodeData)
+		if err != nil {
+			return nil
+		}
+		code = c
+	case "CODE128A":
+		c, err := barcode.EncodeCode128A(req.BarcodeData)
+		if err != nil {
+			return nil
+		}
+		code = c
+	case "GS1128":
+		elements, err := barcode.ParseGS1(req.BarcodeData)
+		if err != nil {
+			return nil
+		}
+		code = barcode.EncodeGS1128(elements)
+	default:
+		return nil
 	}
 
-	// 返回成功
-	sendSuccess(w, "打印成功")
+	moduleWidth := 3 // 打印机默认模块宽度
+	if req.RenderMode == "raster" {
+		moduleWidth = rasterModuleWidth(req)
+	} else if req.BarcodeWidth >= 2 && req.BarcodeWidth <= 6 {
+		moduleWidth = req.BarcodeWidth
+	}
+	return &BarcodeSize{Modules: code.Width(), Dots: code.Width() * moduleWidth}
 }
 
 // 计算EAN-13校验码
@@ -161,59 +384,219 @@ func calculateEAN13CheckDigit(code string) string {
 	return code + fmt.Sprintf("%d", checkDigit)
 }
 
-// 打印条形码
-func printBarcode(req *PrintRequest) error {
-	// 打开LPT1端口
-	printer, err := os.OpenFile(`\\.\LPT1`, os.O_WRONLY, 0644)
+// 生成条形码指令并加入打印队列，数据有误时直接返回错误
+// 设置 sequence 时每个序列号一个标签，每个标签打印 copies 份，全部标签作为一个任务
+func submitBarcode(req *PrintRequest) (*Job, *SequenceResult, error) {
+	p, err := getPrinter(req.Printer)
 	if err != nil {
-		// 尝试其他格式
-		printer, err = os.OpenFile("LPT1", os.O_WRONLY, 0644)
+		return nil, nil, err
+	}
+
+	summary := strings.ToUpper(req.BarcodeType) + " "
+	seq := req.Sequence
+	if seq == nil {
+		cmds, err := buildCommands(p, req)
 		if err != nil {
-			return fmt.Errorf("无法打开打印机端口: %v", err)
+			return nil, nil, err
 		}
+		job, err := jobs.Submit(p.Name, summary+req.BarcodeData+copiesSuffix(req.Copies), bytes.Repeat(cmds, req.Copies))
+		return job, nil, err
 	}
-	defer printer.Close()
+
+	var (
+		job    *Job
+		result *SequenceResult
+		cmds   bytes.Buffer
+	)
+	build := func(first int64) (int64, error) {
+		if err := seq.checkRange(first); err != nil {
+			return 0, err
+		}
+		item := *req
+		for i := 0; i < seq.Count; i++ {
+			item.BarcodeData = seq.value(first, i)
+			data, err := buildCommands(p, &item)
+			if err != nil {
+				return 0, fmt.Errorf("序列号 %s: %w", item.BarcodeData, err)
+			}
+			cmds.Write(bytes.Repeat(data, req.Copies))
+		}
+		// 在保存计数器之前检查任务大小，超出上限的任务不占用序列号
+		if cmds.Len() > maxJobData {
+			return 0, &JobSizeError{Size: cmds.Len(), Limit: maxJobData}
+		}
+
+		next := seq.next(first)
+		result = &SequenceResult{First: seq.value(first, 0), Last: item.BarcodeData, Count: seq.Count}
+		if seq.Counter != "" {
+			result.Next = &next
+		}
+		return next, nil
+	}
+	submit := func() error {
+		var err error
+		job, err = jobs.Submit(p.Name, summary+result.First+" ~ "+result.Last+copiesSuffix(req.Copies), cmds.Bytes())
+		return err
+	}
+
+	if seq.Counter == "" {
+		if _, err = build(seq.Start); err == nil {
+			err = submit()
+		}
+	} else {
+		// 先保存计数器再提交任务，提交失败只会跳号
+		err = counters.Use(seq.Counter, seq.Start, build, submit)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, result, nil
+}
+
+// 任务摘要中的份数
+func copiesSuffix(copies int) string {
+	if copies > 1 {
+		return fmt.Sprintf(" ×%d", copies)
+	}
+	return ""
+}
+
+// 打开打印机传输通道并一次写入全部指令
+// 只由打印机的工作协程调用，同一打印机的任务依次写入，指令不会交错
+// 关闭失败（如虚拟打印机保存图片失败）同样返回错误；此时数据已全部发送，按部分写入处理，不自动重试
+func sendToPrinter(p *Printer, cmds []byte) (err error) {
+	printer, err := p.open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := printer.Close(); cerr != nil && err == nil {
+			err = &partialWriteError{sent: len(cmds), total: len(cmds), err: fmt.Errorf("关闭打印机失败: %w", cerr)}
+		}
+	}()
+
+	n, err := printer.Write(cmds)
+	if err != nil && n > 0 {
+		return &partialWriteError{sent: n, total: len(cmds), err: err}
+	}
+	if err != nil {
+		return fmt.Errorf("写入打印机失败: %w", err)
+	}
+	return nil
+}
+
+// 生成打印条形码的 ESC/POS 指令
+func buildCommands(p *Printer, req *PrintRequest) ([]byte, error) {
+	var buf bytes.Buffer
 
 	// 初始化打印机 (ESC @)
-	printer.Write([]byte("\x1B\x40"))
+	buf.Write([]byte("\x1B\x40"))
+
+	writeBarcodeSettings(&buf, req)
+
+	// 设置居中
+	if req.Center {
+		buf.Write([]byte("\x1B\x61\x01"))
+	}
+
+	// 添加空行（确保条形码上方有空间）
+	buf.Write([]byte("\n"))
+
+	if err := writeBarcode(&buf, p, req); err != nil {
+		return nil, err
+	}
+
+	// 添加足够的换行确保条形码完整打印
+	buf.Write([]byte("\n\n\n"))
 
+	// 取消居中
+	if req.Center {
+		buf.Write([]byte("\x1B\x61\x00"))
+	}
+
+	// 切纸
+	if req.Cut {
+		// 走纸一段距离
+		buf.Write([]byte("\n\n\n\n"))
+		// GS V m (切纸)
+		buf.Write([]byte{0x1D, 0x56, 0x00}) // 全切
+	}
+
+	return buf.Bytes(), nil
+}
+
+// 设置条码高度、模块宽度和 HRI 文字位置
+func writeBarcodeSettings(w io.Writer, req *PrintRequest) {
 	// 设置条形码高度
 	// GS h n
-	printer.Write([]byte{0x1D, 0x68, byte(req.BarcodeHeight)})
+	w.Write([]byte{0x1D, 0x68, byte(req.BarcodeHeight)})
 
 	// 设置条形码宽度
 	// GS w n (n = 2-6)
 	if req.BarcodeWidth >= 2 && req.BarcodeWidth <= 6 {
-		printer.Write([]byte{0x1D, 0x77, byte(req.BarcodeWidth)})
+		w.Write([]byte{0x1D, 0x77, byte(req.BarcodeWidth)})
 	}
 
 	// 设置是否打印条形码下方的文字
 	// GS H n (0=不打印, 1=上方, 2=下方, 3=上下都打印)
 	if req.ShowText {
-		printer.Write([]byte{0x1D, 0x48, 0x02}) // 下方打印
+		w.Write([]byte{0x1D, 0x48, 0x02}) // 下方打印
 	} else {
-		printer.Write([]byte{0x1D, 0x48, 0x00}) // 不打印
+		w.Write([]byte{0x1D, 0x48, 0x00}) // 不打印
 	}
+}
 
-	// 设置居中
-	if req.Center {
-		printer.Write([]byte("\x1B\x61\x01"))
+// 在条码下方打印的文字：换行以外的控制字符显示为空格，与打印机的 HRI 一致
+// 否则数据中的 ESC、GS 等字节会被打印机当作指令执行
+func hriText(s string) []byte {
+	text := []byte(s)
+	for i, c := range text {
+		if c < 0x20 && c != '\n' || c == 0x7F {
+			text[i] = ' '
+		}
 	}
+	return text
+}
 
-	// 添加空行（确保条形码上方有空间）
-	printer.Write([]byte("\n"))
+// 校验数据并写入条码：固件指令或软件编码的光栅图
+func writeBarcode(w io.Writer, p *Printer, req *PrintRequest) error {
+	if err := validateBarcode(req); err != nil {
+		return err
+	}
+	if useRaster(p, req) {
+		raster, text, err := renderRaster(req)
+		if err != nil {
+			return err
+		}
+		// 超出打印宽度的光栅图会被打印机截断或整张不打印
+		if width := p.Config.printWidth(); raster.Width > width {
+			return &WidthError{Width: raster.Width, PrintWidth: width}
+		}
+		writeRasterImage(w, raster, text, req)
+		return nil
+	}
+	return writeFirmwareBarcode(w, req)
+}
 
-	// 选择条形码类型并打印
+// 使用打印机固件指令 (GS k) 打印条形码
+func writeFirmwareBarcode(printer io.Writer, req *PrintRequest) error {
 	barcodeType := strings.ToUpper(req.BarcodeType)
 	
 	switch barcodeType {
 	case "CODE128A":
-		// 使用备选的 CODE128 格式
-		// m=5 自动选择子集
-		printer.Write([]byte{0x1D, 0x6B, 0x05})
-		printer.Write([]byte(req.BarcodeData))
-		printer.Write([]byte{0x00}) // NULL 结束符
-		
+		// 只使用 A 子集，数据以 {A 开头
+		// m=73 的长度字节允许数据中含 NUL 等控制字符
+		code, err := barcode.EncodeCode128A(req.BarcodeData)
+		if err != nil {
+			return err
+		}
+		payload := code.Payload()
+		if len(payload) > 255 {
+			return fmt.Errorf("CODE128条形码数据过长（编码后%d字节，最多255字节）", len(payload))
+		}
+		printer.Write([]byte{0x1D, 0x6B, 0x49, byte(len(payload))})
+		printer.Write(payload)
+
 	case "CODE39":
 		// 使用简单格式 GS k m d1...dn (结束符为 NULL)
 		// m=4 是 CODE39
@@ -221,6 +604,22 @@ func printBarcode(req *PrintRequest) error {
 		printer.Write([]byte(req.BarcodeData))
 		printer.Write([]byte{0x00}) // NULL 结束符
 		
+	case "CODE93":
+		// 全ASCII，C、K 校验字符由打印机自动添加
+		data := []byte(req.BarcodeData)
+		if len(data) == 0 || len(data) > 255 {
+			return fmt.Errorf("CODE93条形码必须是1-255个字符")
+		}
+		for i, c := range data {
+			if c > 127 {
+				return fmt.Errorf("CODE93第%d个字符无效，只支持ASCII字符", i+1)
+			}
+		}
+		// GS k m n d1...dn
+		// m=72 是 CODE93 的格式
+		printer.Write([]byte{0x1D, 0x6B, 0x48, byte(len(data))})
+		printer.Write(data)
+
 	case "EAN13":
 		// 处理EAN-13，自动计算校验码
 		ean13 := calculateEAN13CheckDigit(req.BarcodeData)
@@ -232,46 +631,141 @@ func printBarcode(req *PrintRequest) error {
 		printer.Write([]byte{0x1D, 0x6B, 0x02})
 		printer.Write([]byte(ean13))
 		
+	case "ISBN", "ISSN":
+		// 图书和期刊条码，转换为 EAN-13 并校验前缀
+		normalize := barcode.NormalizeISBN
+		if barcodeType == "ISSN" {
+			normalize = barcode.NormalizeISSN
+		}
+		ean13, err := normalize(req.BarcodeData)
+		if err != nil {
+			return err
+		}
+		printer.Write([]byte{0x1D, 0x6B, 0x02})
+		printer.Write([]byte(ean13))
+
 	case "EAN8":
 		// GS k m d1...d8
-		// m=3 是 EAN8 的格式
-		if len(req.BarcodeData) != 8 {
-			return fmt.Errorf("EAN8条形码必须是8位数字")
+		// m=3 是 EAN8 的格式，7位时自动计算校验码
+		ean8 := req.BarcodeData
+		if len(ean8) == 7 {
+			ean8 += fmt.Sprintf("%d", barcode.EANCheckDigit(ean8))
+		}
+		if len(ean8) != 8 {
+			return fmt.Errorf("EAN8条形码必须是7或8位数字")
 		}
 		printer.Write([]byte{0x1D, 0x6B, 0x03})
-		printer.Write([]byte(req.BarcodeData))
-		
+		printer.Write([]byte(ean8))
+
+	case "UPCA":
+		// 6-8位视为UPC-E，展开为UPC-A
+		data := req.BarcodeData
+		if len(data) >= 6 && len(data) <= 8 {
+			upce, err := barcode.NormalizeUPCE(data)
+			if err != nil {
+				return err
+			}
+			data, _ = barcode.ExpandUPCE(upce)
+		}
+		// 11位时自动计算校验码，12位的校验码已在 validateBarcode 中校验
+		if len(data) == 11 {
+			data += fmt.Sprintf("%d", barcode.EANCheckDigit(data))
+		}
+		if len(data) != 12 {
+			return fmt.Errorf("UPC-A条形码必须是11或12位数字")
+		}
+		// GS k m n d1...d12
+		// m=65 是 UPC-A 的格式
+		printer.Write([]byte{0x1D, 0x6B, 0x41, 12})
+		printer.Write([]byte(data))
+
+	case "UPCE":
+		// 接受6-8位UPC-E，或可零压缩的11-12位UPC-A
+		upce, err := barcode.NormalizeUPCE(req.BarcodeData)
+		if err != nil {
+			return err
+		}
+		// GS k m n d1...d8（数字系统 + 6位 + 校验码）
+		// m=66 是 UPC-E 的格式
+		printer.Write([]byte{0x1D, 0x6B, 0x42, 8})
+		printer.Write([]byte(upce))
+
+	case "ITF", "ITF14":
+		data := req.BarcodeData
+		if barcodeType == "ITF14" {
+			// 处理ITF-14，自动计算GTIN-14校验码
+			itf14, err := barcode.NormalizeITF14(data)
+			if err != nil {
+				return err
+			}
+			data = itf14
+		} else if len(data)%2 != 0 {
+			return fmt.Errorf("ITF条形码必须是偶数位数字（当前%d位），可在前面补0", len(data))
+		}
+		if len(data) > 254 {
+			return fmt.Errorf("ITF条形码数据过长（最多254位）")
+		}
+		// GS k m n d1...dn
+		// m=70 是 ITF 的格式（固件通常不画保护框，需要保护框请使用光栅模式）
+		printer.Write([]byte{0x1D, 0x6B, 0x46, byte(len(data))})
+		printer.Write([]byte(data))
+
+	case "CODABAR":
+		// 校验起始符/终止符和数据字符
+		codabar, err := barcode.NormalizeCodabar(req.BarcodeData)
+		if err != nil {
+			return err
+		}
+		if len(codabar) > 255 {
+			return fmt.Errorf("CODABAR条形码数据过长（最多255个字符）")
+		}
+		// GS k m n d1...dn
+		// m=71 是 CODABAR (NW-7) 的格式
+		printer.Write([]byte{0x1D, 0x6B, 0x47, byte(len(codabar))})
+		printer.Write([]byte(codabar))
+
+	case "QR":
+		return writeQRCode(printer, req)
+
+	case "PDF417":
+		return writePDF417(printer, req)
+
+	case "GS1128":
+		elements, err := barcode.ParseGS1(req.BarcodeData)
+		if err != nil {
+			return err
+		}
+		// 以 FNC1 ({1) 开头，可变长度 AI 之后插入 FNC1 分隔符
+		payload := barcode.EncodeGS1128(elements).Payload()
+		if len(payload) > 255 {
+			return fmt.Errorf("GS1-128条形码数据过长（编码后%d字节，最多255字节）", len(payload))
+		}
+		// 固件的 HRI 无法显示 AI 括号，关闭后自行打印
+		printer.Write([]byte{0x1D, 0x48, 0x00})
+		printer.Write([]byte{0x1D, 0x6B, 0x49, byte(len(payload))})
+		printer.Write(payload)
+		if req.ShowText {
+			printer.Write([]byte("\n" + barcode.GS1Text(elements)))
+		}
+
 	case "CODE128":
 		fallthrough
 	default:
-		// 使用扩展格式确保使用 CODE128-B 子集
+		// 按最短符号序列自动切换 A/B/C 子集
 		// GS k m n d1...dn
 		// m=73 (0x49) 是 CODE128 的扩展格式
-		data := []byte(req.BarcodeData)
-		
-		// 创建完整的数据包，包含子集选择
-		fullData := make([]byte, 0, len(data)+2)
-		fullData = append(fullData, 0x7B, 0x42) // {B 表示使用 CODE128-B 子集（支持大小写字母）
-		fullData = append(fullData, data...)
-		
-		printer.Write([]byte{0x1D, 0x6B, 0x49, byte(len(fullData))})
-		printer.Write(fullData)
-	}
-
-	// 添加足够的换行确保条形码完整打印
-	printer.Write([]byte("\n\n\n"))
-
-	// 取消居中
-	if req.Center {
-		printer.Write([]byte("\x1B\x61\x00"))
-	}
+		code, err := barcode.EncodeCode128(req.BarcodeData)
+		if err != nil {
+			return err
+		}
 
-	// 切纸
-	if req.Cut {
-		// 走纸一段距离
-		printer.Write([]byte("\n\n\n\n"))
-		// GS V m (切纸)
-		printer.Write([]byte{0x1D, 0x56, 0x00}) // 全切
+		// 扩展格式的长度只有一个字节
+		payload := code.Payload()
+		if len(payload) > 255 {
+			return fmt.Errorf("CODE128条形码数据过长（编码后%d字节，最多255字节）", len(payload))
+		}
+		printer.Write([]byte{0x1D, 0x6B, 0x49, byte(len(payload))})
+		printer.Write(payload)
 	}
 
 	return nil
@@ -300,6 +794,68 @@ func sendError(w http.ResponseWriter, message string) {
 	})
 }
 
+// 发送打印错误，结构化错误附带错误代码和详情
+func sendPrintError(w http.ResponseWriter, err error) {
+	resp := printErrorResponse(err)
+	status := http.StatusBadRequest
+	var busy *BusyError
+	if errors.As(err, &busy) {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// 错误对应的响应内容
+func printErrorResponse(err error) PrintResponse {
+	resp := PrintResponse{
+		Status:  "error",
+		Message: err.Error(),
+	}
+	var de detailedError
+	if errors.As(err, &de) {
+		resp.Code = de.ErrorCode()
+		resp.Detail = de
+	}
+	return resp
+}
+
+// 发送任务已入队的响应，resp 中可预先设置条码宽度等内容
+// 请求带 wait=true 时等待打印结束：超时未开始打印返回 503，打印失败返回 502
+func sendJobResponse(w http.ResponseWriter, r *http.Request, job *Job, resp PrintResponse) {
+	resp.Status, resp.JobID = "success", job.ID
+	if resp.Message == "" {
+		resp.Message = "已加入打印队列"
+	}
+
+	if wait, _ := strconv.ParseBool(r.URL.Query().Get("wait")); wait {
+		p, err := getPrinter(job.Printer)
+		if err == nil {
+			job, err = jobs.Wait(job.ID, p.Config.waitTimeout())
+		}
+		if err != nil {
+			sendPrintError(w, err)
+			return
+		}
+		if job.Status != JobDone {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(PrintResponse{
+				Status:  "error",
+				Message: "打印失败: " + job.Error,
+				JobID:   job.ID,
+			})
+			return
+		}
+		resp.Message = strings.Replace(resp.Message, "已加入打印队列", "打印成功", 1)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 // 发送成功响应
 func sendSuccess(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -419,6 +975,22 @@ const testHTML = `
         button:active {
             transform: translateY(1px);
         }
+        .preview-btn {
+            background: #6c757d;
+        }
+        .preview-btn:hover {
+            background: #545b62;
+        }
+        .preview {
+            margin-top: 20px;
+            text-align: center;
+            display: none;
+        }
+        .preview img {
+            max-width: 100%;
+            border: 1px solid #ddd;
+            box-shadow: 0 1px 4px rgba(0,0,0,0.1);
+        }
         .status {
             margin-top: 20px;
             padding: 15px;
@@ -472,10 +1044,23 @@ const testHTML = `
                 <label>条形码类型：</label>
                 <select id="barcode-type" onchange="updateBarcodeInfo()">
                     <option value="CODE128">CODE128（推荐，支持UUID）</option>
-                    <option value="CODE128A">CODE128 格式2（备选）</option>
+                    <option value="CODE128A">CODE128 A 子集</option>
                     <option value="CODE39">CODE39</option>
                     <option value="EAN13">EAN-13（商品条码）</option>
                     <option value="EAN8">EAN-8（短条码）</option>
+                    <option value="ISBN">ISBN（图书）</option>
+                    <option value="ISSN">ISSN（期刊）</option>
+                    <option value="UPCA">UPC-A（北美商品条码）</option>
+                    <option value="UPCE">UPC-E（UPC短条码）</option>
+                    <option value="GS1128">GS1-128（供应链标签）</option>
+                    <option value="CODE93">CODE93（物流）</option>
+                    <option value="ITF">ITF（交叉25码）</option>
+                    <option value="ITF14">ITF-14（外箱条码）</option>
+                    <option value="CODABAR">CODABAR（NW-7，图书馆/血库）</option>
+                    <option value="QR">QR码（二维码）</option>
+                    <option value="PDF417">PDF417（二维码）</option>
+                    <option value="DATAMATRIX">Data Matrix（二维码）</option>
+                    <option value="GS1DATAMATRIX">GS1 Data Matrix</option>
                 </select>
             </div>
             
@@ -491,6 +1076,7 @@ const testHTML = `
                 <button class="example-btn" onclick="setExample('product')">商品编号</button>
                 <button class="example-btn" onclick="setExample('order')">订单号</button>
                 <button class="example-btn" onclick="setExample('ean13')">EAN-13示例</button>
+                <button class="example-btn" onclick="setExample('qr')">支付链接</button>
             </div>
             
             <div class="control-group">
@@ -524,10 +1110,48 @@ const testHTML = `
                     <input type="number" id="barcode-height" value="80" min="30" max="255">
                 </label>
             </div>
+
+            <div class="control-group" id="qr-options" style="display: none;">
+                <label>
+                    纠错等级：
+                    <select id="qr-level">
+                        <option value="L">L（7%）</option>
+                        <option value="M" selected>M（15%）</option>
+                        <option value="Q">Q（25%）</option>
+                        <option value="H">H（30%）</option>
+                    </select>
+                </label>
+                <label>
+                    模块大小：
+                    <input type="number" id="qr-size" value="6" min="1" max="16">
+                </label>
+            </div>
+
+            <div class="control-group" id="addon-options" style="display: none;">
+                <label>
+                    附加码：
+                    <input type="text" id="barcode-addon" placeholder="2位或5位数字，可留空" maxlength="5">
+                </label>
+            </div>
+
+            <div class="control-group">
+                <label>
+                    渲染方式：
+                    <select id="render-mode">
+                        <option value="firmware" selected>打印机固件（GS k）</option>
+                        <option value="raster">软件光栅图（GS v 0）</option>
+                    </select>
+                </label>
+            </div>
         </div>
 
+        <button onclick="previewBarcode()" class="preview-btn">👁️ 预览</button>
         <button onclick="printBarcode()">🖨️ 打印条形码</button>
 
+        <div id="preview" class="preview">
+            <img id="preview-image" alt="打印预览">
+        </div>
+
         <div id="status" class="status"></div>
     </div>
 
@@ -537,14 +1161,17 @@ const testHTML = `
         const type = document.getElementById('barcode-type').value;
         const info = document.getElementById('barcode-info');
         const dataInput = document.getElementById('barcode-data');
-        
+
+        document.getElementById('qr-options').style.display = type === 'QR' ? 'flex' : 'none';
+        document.getElementById('addon-options').style.display = ['EAN13', 'UPCA', 'UPCE', 'ISBN', 'ISSN'].includes(type) ? 'flex' : 'none';
+
         switch(type) {
             case 'CODE128':
-                info.textContent = 'CODE128：支持所有ASCII字符，使用CODE128-B子集（支持大小写字母）';
+                info.textContent = 'CODE128：支持所有ASCII字符，自动切换A/B/C子集，连续数字更紧凑';
                 dataInput.placeholder = '输入条形码数据（如UUID）';
                 break;
             case 'CODE128A':
-                info.textContent = 'CODE128 格式2：备选格式，如果主格式无法正确扫描可尝试此选项';
+                info.textContent = 'CODE128 A 子集：只支持大写字母、数字、标点和控制字符';
                 dataInput.placeholder = '输入条形码数据';
                 break;
             case 'CODE39':
@@ -556,9 +1183,61 @@ const testHTML = `
                 dataInput.placeholder = '输入12或13位数字';
                 break;
             case 'EAN8':
-                info.textContent = 'EAN-8：必须是8位数字（用于小型商品）';
+                info.textContent = 'EAN-8：输入7位数字（自动计算第8位校验码）或完整8位（用于小型商品）';
                 dataInput.placeholder = '输入8位数字';
                 break;
+            case 'ISBN':
+                info.textContent = 'ISBN：输入ISBN-10或978/979开头的ISBN-13，可填写5位价格附加码';
+                dataInput.placeholder = '如 978-7-111-12345-6';
+                break;
+            case 'ISSN':
+                info.textContent = 'ISSN：输入8位ISSN或977开头的13位条码，可填写2位期号附加码';
+                dataInput.placeholder = '如 0317-8471';
+                break;
+            case 'UPCA':
+                info.textContent = 'UPC-A：输入11位数字（自动计算第12位校验码），也可输入UPC-E自动展开';
+                dataInput.placeholder = '输入11或12位数字';
+                break;
+            case 'UPCE':
+                info.textContent = 'UPC-E：输入6-8位UPC-E，或可零压缩的11-12位UPC-A（自动压缩）';
+                dataInput.placeholder = '输入6-8位或11-12位数字';
+                break;
+            case 'GS1128':
+                info.textContent = 'GS1-128：以(AI)数据格式输入，自动插入FNC1并校验长度、日期和校验码';
+                dataInput.placeholder = '如 (01)09501101530003(17)250101';
+                break;
+            case 'CODE93':
+                info.textContent = 'CODE93：支持所有ASCII字符，比CODE39更紧凑，自动添加C、K校验字符';
+                dataInput.placeholder = '输入条形码数据';
+                break;
+            case 'ITF':
+                info.textContent = 'ITF：交叉25码，必须是偶数位数字，光栅模式带保护框';
+                dataInput.placeholder = '输入偶数位数字';
+                break;
+            case 'ITF14':
+                info.textContent = 'ITF-14：外箱条码，输入13位数字（自动计算第14位校验码），建议使用光栅模式打印保护框';
+                dataInput.placeholder = '输入13或14位数字';
+                break;
+            case 'CODABAR':
+                info.textContent = 'CODABAR：以A/B/C/D开头和结尾，中间为数字和 - $ : / . +';
+                dataInput.placeholder = '如 A40156B';
+                break;
+            case 'PDF417':
+                info.textContent = 'PDF417：二维堆叠码，适合快递面单和证件，最多约1800个字符';
+                dataInput.placeholder = '输入文本';
+                break;
+            case 'DATAMATRIX':
+                info.textContent = 'Data Matrix：小尺寸二维码，适合电子元器件标签，以光栅图打印';
+                dataInput.placeholder = '输入文本';
+                break;
+            case 'GS1DATAMATRIX':
+                info.textContent = 'GS1 Data Matrix：按 (AI)数据 格式输入，如 (01)09501101530003(17)250101';
+                dataInput.placeholder = '(01)09501101530003(17)250101';
+                break;
+            case 'QR':
+                info.textContent = 'QR码：支持网址、文本等任意内容，适合支付链接和物流追踪';
+                dataInput.placeholder = '输入网址或文本';
+                break;
         }
     }
 
@@ -592,12 +1271,16 @@ const testHTML = `
                 typeSelect.value = 'EAN13';
                 dataInput.value = '690123456789';  // 12位，自动计算校验码
                 break;
+            case 'qr':
+                typeSelect.value = 'QR';
+                dataInput.value = 'https://pay.example.com/order/ORD20240115001';
+                break;
         }
         updateBarcodeInfo();
     }
 
-    // 打印条形码
-    async function printBarcode() {
+    // 收集并检查表单数据，数据无效时返回 null
+    function buildPrintData() {
         const barcodeType = document.getElementById('barcode-type').value;
         const barcodeData = document.getElementById('barcode-data').value;
         const showText = document.getElementById('barcode-showText').checked;
@@ -605,35 +1288,48 @@ const testHTML = `
         const cut = document.getElementById('barcode-cut').checked;
         const width = parseInt(document.getElementById('barcode-width').value);
         const height = parseInt(document.getElementById('barcode-height').value);
+        const renderMode = document.getElementById('render-mode').value;
 
         if (!barcodeData.trim()) {
             showStatus('请输入条形码数据', 'error');
-            return;
+            return null;
         }
 
         // 验证条形码数据
         if (barcodeType === 'EAN13' && barcodeData.length !== 12 && barcodeData.length !== 13) {
             showStatus('EAN-13条形码必须是12或13位数字', 'error');
-            return;
+            return null;
         }
-        if (barcodeType === 'EAN8' && barcodeData.length !== 8) {
-            showStatus('EAN-8条形码必须是8位数字', 'error');
-            return;
+        if (barcodeType === 'EAN8' && barcodeData.length !== 7 && barcodeData.length !== 8) {
+            showStatus('EAN-8条形码必须是7或8位数字', 'error');
+            return null;
         }
         if (barcodeType === 'CODE39' && !/^[A-Z0-9\-\.\$\/\+\%\s]*$/.test(barcodeData)) {
             showStatus('CODE39只支持大写字母、数字和部分符号', 'error');
-            return;
+            return null;
         }
 
-        const printData = {
+        return {
             barcodeType: barcodeType,
             barcodeData: barcodeData,
             showText: showText,
             center: center,
             cut: cut,
             barcodeWidth: width,
-            barcodeHeight: height
+            barcodeHeight: height,
+            renderMode: renderMode,
+            qrErrorLevel: document.getElementById('qr-level').value,
+            qrModuleSize: parseInt(document.getElementById('qr-size').value),
+            addon: document.getElementById('addon-options').style.display === 'none' ? '' : document.getElementById('barcode-addon').value.trim()
         };
+    }
+
+    // 打印条形码
+    async function printBarcode() {
+        const printData = buildPrintData();
+        if (!printData) {
+            return;
+        }
 
         showStatus('正在打印...', 'success');
 
@@ -649,7 +1345,8 @@ const testHTML = `
             const result = await response.json();
             
             if (result.status === 'success') {
-                showStatus('✅ 打印成功！', 'success');
+                showStatus('已加入打印队列，等待打印...', 'success');
+                waitForJob(result.jobId);
             } else {
                 showStatus('❌ 打印失败：' + result.message, 'error');
             }
@@ -658,6 +1355,71 @@ const testHTML = `
         }
     }
 
+    // 轮询打印任务状态，最多等待 30 秒
+    async function waitForJob(jobId) {
+        for (let i = 0; i < 60; i++) {
+            await new Promise(resolve => setTimeout(resolve, 500));
+            try {
+                const response = await fetch('http://localhost:9100/api/jobs/' + jobId);
+                const job = await response.json();
+                if (job.status === 'done') {
+                    showStatus('✅ 打印成功！', 'success');
+                    return;
+                }
+                if (job.status === 'failed') {
+                    showStatus('❌ 打印失败：' + job.error, 'error');
+                    return;
+                }
+                if (job.status === 'cancelled') {
+                    showStatus('打印任务已取消', 'error');
+                    return;
+                }
+                if (job.status === 'printing') {
+                    showStatus('正在打印...', 'success');
+                }
+                if (job.status === 'retrying') {
+                    showStatus('第' + job.attempts + '次打印失败，等待重试：' + job.error, 'error');
+                }
+            } catch (error) {
+                showStatus('❌ 连接失败：' + error.message, 'error');
+                return;
+            }
+        }
+        showStatus('打印任务仍在队列中（任务 ' + jobId + '），请检查打印机', 'error');
+    }
+
+    // 预览打印效果
+    async function previewBarcode() {
+        const printData = buildPrintData();
+        if (!printData) {
+            return;
+        }
+
+        try {
+            const response = await fetch('http://localhost:9100/api/preview', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json'
+                },
+                body: JSON.stringify(printData)
+            });
+
+            if (!response.ok || !response.headers.get('Content-Type').startsWith('image/')) {
+                const result = await response.json();
+                showStatus('❌ 预览失败：' + result.message, 'error');
+                return;
+            }
+
+            const img = document.getElementById('preview-image');
+            URL.revokeObjectURL(img.src);
+            img.src = URL.createObjectURL(await response.blob());
+            document.getElementById('preview').style.display = 'block';
+            document.getElementById('status').style.display = 'none';
+        } catch (error) {
+            showStatus('❌ 连接失败：' + error.message, 'error');
+        }
+    }
+
     // 显示状态信息
     function showStatus(message, type) {
         const statusDiv = document.getElementById('status');
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "重新生成 testdata/commands 中的 golden 文件")
+
+// 与 testdata/commands/<name>.golden 比较，使用 -update 时重新生成
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "commands", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 golden 文件失败（使用 -update 生成）: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("指令与 %s 不一致（确认变更后使用 -update 更新）\n--- 实际\n%s--- 期望\n%s", path, got, want)
+	}
+}
+
+// 固件打印机和只支持光栅 QR 码、PDF417 的打印机
+var (
+	firmwarePrinter     = &Printer{Name: "firmware"}
+	rasterQRPrinter     = &Printer{Name: "raster-qr", Config: PrinterConfig{RasterTypes: []string{"QR"}}}
+	rasterPDF417Printer = &Printer{Name: "raster-pdf417", Config: PrinterConfig{RasterTypes: []string{"QR", "PDF417"}}}
+)
+
+type commandCase struct {
+	name    string
+	printer *Printer
+	req     PrintRequest
+}
+
+func commandCases() []commandCase {
+	cases := []commandCase{
+		// 各条码类型（固件指令）
+		{name: "code128", req: PrintRequest{BarcodeType: "CODE128", BarcodeData: "ABC-1234567890"}},
+		{name: "code128-default-type", req: PrintRequest{BarcodeData: "Hello{World}"}},
+		{name: "code128-control", req: PrintRequest{BarcodeType: "CODE128", BarcodeData: "A\tB\x01c"}},
+		{name: "code128a", req: PrintRequest{BarcodeType: "CODE128A", BarcodeData: "ABC123"}},
+		{name: "code128a-control", req: PrintRequest{BarcodeType: "CODE128A", BarcodeData: "AB\x00\tC"}},
+		{name: "gs1128", req: PrintRequest{BarcodeType: "GS1128", BarcodeData: "(01)09501101530003(10)AB12(17)250101", ShowText: true}},
+		{name: "gs1128-map", req: PrintRequest{BarcodeType: "GS1128", GS1Data: map[string]string{"10": "LOT7", "01": "09501101530003"}}},
+		{name: "code39", req: PrintRequest{BarcodeType: "CODE39", BarcodeData: "CODE-39 $/+%"}},
+		{name: "code93", req: PrintRequest{BarcodeType: "CODE93", BarcodeData: "Code93-abc"}},
+		{name: "ean13", req: PrintRequest{BarcodeType: "EAN13", BarcodeData: "590123412345"}},
+		{name: "ean13-check", req: PrintRequest{BarcodeType: "EAN13", BarcodeData: "5901234123457"}},
+		{name: "ean8", req: PrintRequest{BarcodeType: "EAN8", BarcodeData: "9638507"}},
+		{name: "isbn", req: PrintRequest{BarcodeType: "ISBN", BarcodeData: "0-306-40615-2"}},
+		{name: "issn", req: PrintRequest{BarcodeType: "ISSN", BarcodeData: "0317-8471"}},
+		{name: "upca", req: PrintRequest{BarcodeType: "UPCA", BarcodeData: "03600029145"}},
+		{name: "upca-check", req: PrintRequest{BarcodeType: "UPCA", BarcodeData: "036000291452"}},
+		{name: "upca-from-upce", req: PrintRequest{BarcodeType: "UPCA", BarcodeData: "01234565"}},
+		{name: "upce", req: PrintRequest{BarcodeType: "UPCE", BarcodeData: "01234565"}},
+		{name: "itf", req: PrintRequest{BarcodeType: "ITF", BarcodeData: "12345678"}},
+		{name: "itf14", req: PrintRequest{BarcodeType: "ITF14", BarcodeData: "1540014128876"}},
+		{name: "codabar", req: PrintRequest{BarcodeType: "CODABAR", BarcodeData: "a40156b"}},
+		{name: "qr", req: PrintRequest{BarcodeType: "QR", BarcodeData: "https://example.com", ShowText: true}},
+		{name: "qr-hri-control", req: PrintRequest{BarcodeType: "QR", BarcodeData: "A\x1b@B\x1dV\x00\nC", ShowText: true}},
+		{name: "qr-options", req: PrintRequest{BarcodeType: "QR", BarcodeData: "HELLO", QRErrorLevel: "h", QRModuleSize: 10, QRModel: 1}},
+		{name: "pdf417", req: PrintRequest{BarcodeType: "PDF417", BarcodeData: "PDF417 test data"}},
+		{name: "pdf417-hri-control", req: PrintRequest{BarcodeType: "PDF417", BarcodeData: "A\x1bd\x05B", ShowText: true}},
+		{name: "pdf417-options", req: PrintRequest{BarcodeType: "PDF417", BarcodeData: "1234567890", PDF417Columns: 4, PDF417Rows: 10, PDF417ErrorLevel: 2}},
+
+		// 光栅图
+		{name: "raster-code128", req: PrintRequest{BarcodeType: "CODE128", BarcodeData: "RASTER", RenderMode: "raster", BarcodeHeight: 20, ShowText: true}},
+		{name: "raster-code128-hri-control", req: PrintRequest{BarcodeType: "CODE128", BarcodeData: "A\x1bi", RenderMode: "raster", BarcodeHeight: 4, ShowText: true}},
+		{name: "raster-ean13-addon", req: PrintRequest{BarcodeType: "EAN13", BarcodeData: "978030640615", Addon: "51234", BarcodeWidth: 2, BarcodeHeight: 20}},
+		{name: "raster-itf14", req: PrintRequest{BarcodeType: "ITF14", BarcodeData: "1540014128876", RenderMode: "raster", BarcodeWidth: 1, BarcodeHeight: 10}},
+		{name: "raster-codabar", req: PrintRequest{BarcodeType: "CODABAR", BarcodeData: "A123B", RenderMode: "raster", WideRatio: 2.5, BarcodeWidth: 2, BarcodeHeight: 10}},
+		{name: "raster-qr", req: PrintRequest{BarcodeType: "QR", BarcodeData: "raster", RenderMode: "raster", QRModuleSize: 2}},
+		{name: "raster-qr-printer", printer: rasterQRPrinter, req: PrintRequest{BarcodeType: "QR", BarcodeData: "raster", QRModuleSize: 2, ShowText: true}},
+		{name: "raster-pdf417", req: PrintRequest{BarcodeType: "PDF417", BarcodeData: "PDF417 raster", RenderMode: "raster", PDF417ModuleWidth: 2, PDF417RowHeight: 2}},
+		{name: "raster-pdf417-printer", printer: rasterPDF417Printer, req: PrintRequest{BarcodeType: "PDF417", BarcodeData: "1234567890123", PDF417Columns: 2, PDF417ErrorLevel: 1, PDF417ModuleWidth: 2, PDF417RowHeight: 3}},
+		{name: "datamatrix", req: PrintRequest{BarcodeType: "DATAMATRIX", BarcodeData: "DataMatrix", DataMatrixModuleSize: 2}},
+		{name: "gs1datamatrix", req: PrintRequest{BarcodeType: "GS1DATAMATRIX", BarcodeData: "(01)09501101530003", DataMatrixModuleSize: 2}},
+
+		// 错误
+		{name: "error-unknown-type", req: PrintRequest{BarcodeType: "CODE11", BarcodeData: "123"}},
+		{name: "error-empty", req: PrintRequest{BarcodeType: "CODE128"}},
+		{name: "error-code128-non-ascii", req: PrintRequest{BarcodeType: "CODE128", BarcodeData: "价格100"}},
+		{name: "error-code128a-lowercase", req: PrintRequest{BarcodeType: "CODE128A", BarcodeData: "ABc"}},
+		{name: "error-code128-too-long", req: PrintRequest{BarcodeType: "CODE128", BarcodeData: strings.Repeat("Ab", 130)}},
+		{name: "error-upca-bad-check", req: PrintRequest{BarcodeType: "UPCA", BarcodeData: "036000291453"}},
+		{name: "error-gs1128-bad-check", req: PrintRequest{BarcodeType: "GS1128", BarcodeData: "(01)09501101530004"}},
+		{name: "error-gs1128-map", req: PrintRequest{BarcodeType: "GS1128", GS1Data: map[string]string{"17": "251301"}}},
+		{name: "error-gs1datamatrix-check", req: PrintRequest{BarcodeType: "GS1DATAMATRIX", BarcodeData: "(01)09501101530004(10)AB"}},
+		{name: "error-code39-lowercase", req: PrintRequest{BarcodeType: "CODE39", BarcodeData: "abc"}},
+		{name: "error-ean13-letters", req: PrintRequest{BarcodeType: "EAN13", BarcodeData: "59012341234A"}},
+		{name: "error-ean13-check", req: PrintRequest{BarcodeType: "EAN13", BarcodeData: "5901234123450"}},
+		{name: "error-ean8-length", req: PrintRequest{BarcodeType: "EAN8", BarcodeData: "12345"}},
+		{name: "error-isbn-prefix", req: PrintRequest{BarcodeType: "ISBN", BarcodeData: "9771234567003"}},
+		{name: "error-upce-number-system", req: PrintRequest{BarcodeType: "UPCE", BarcodeData: "21234565"}},
+		{name: "error-itf-odd", req: PrintRequest{BarcodeType: "ITF", BarcodeData: "12345"}},
+		{name: "error-codabar-start", req: PrintRequest{BarcodeType: "CODABAR", BarcodeData: "40156"}},
+		{name: "error-qr-level", req: PrintRequest{BarcodeType: "QR", BarcodeData: "x", QRErrorLevel: "X"}},
+		{name: "error-qr-too-long", req: PrintRequest{BarcodeType: "QR", BarcodeData: strings.Repeat("a", 1274), QRErrorLevel: "H"}},
+		{name: "error-qr-size", req: PrintRequest{BarcodeType: "QR", BarcodeData: "x", QRModuleSize: 17}},
+		{name: "error-pdf417-too-long", req: PrintRequest{BarcodeType: "PDF417", BarcodeData: strings.Repeat("x", 100), PDF417Columns: 1, PDF417Rows: 3}},
+		{name: "error-addon", req: PrintRequest{BarcodeType: "EAN13", BarcodeData: "590123412345", Addon: "123"}},
+		{name: "error-addon-qr", req: PrintRequest{BarcodeType: "QR", BarcodeData: "x", Addon: "12"}},
+		{name: "error-addon-codabar", req: PrintRequest{BarcodeType: "CODABAR", BarcodeData: "A1B", Addon: "12"}},
+		{name: "error-addon-pdf417", req: PrintRequest{BarcodeType: "PDF417", BarcodeData: "x", Addon: "zz"}},
+		{name: "error-addon-datamatrix", req: PrintRequest{BarcodeType: "DATAMATRIX", BarcodeData: "x", Addon: "12"}},
+		{name: "error-render-mode", req: PrintRequest{BarcodeData: "x", RenderMode: "laser"}},
+		{name: "error-height", req: PrintRequest{BarcodeData: "x", BarcodeHeight: 256}},
+		{name: "error-raster-too-wide", req: PrintRequest{BarcodeType: "QR", BarcodeData: strings.Repeat("A", 60), RenderMode: "raster", QRModuleSize: 16}},
+		{name: "error-raster-wide-ratio", req: PrintRequest{BarcodeType: "CODABAR", BarcodeData: "A1B", RenderMode: "raster", WideRatio: 4}},
+	}
+
+	// 模块宽度和高度组合（宽度超出 2-6 时不发送 GS w）
+	for _, width := range []int{1, 2, 3, 6, 7} {
+		for _, height := range []int{1, 100, 255} {
+			cases = append(cases, commandCase{
+				name: fmt.Sprintf("size-w%d-h%d", width, height),
+				req:  PrintRequest{BarcodeData: "SIZE", BarcodeWidth: width, BarcodeHeight: height},
+			})
+		}
+	}
+
+	// showText、center、cut 组合
+	for flags := 0; flags < 8; flags++ {
+		req := PrintRequest{BarcodeData: "FLAGS", ShowText: flags&1 != 0, Center: flags&2 != 0, Cut: flags&4 != 0}
+		cases = append(cases, commandCase{
+			name: fmt.Sprintf("flags-text%t-center%t-cut%t", req.ShowText, req.Center, req.Cut),
+			req:  req,
+		})
+	}
+	return cases
+}
+
+// 生成指令并格式化为 golden 文件内容，出错时记录错误信息
+func commandOutput(p *Printer, req PrintRequest) string {
+	if err := prepareRequest(&req); err != nil {
+		return "error: " + err.Error() + "\n"
+	}
+	cmds, err := buildCommands(p, &req)
+	if err != nil {
+		return "error: " + err.Error() + "\n"
+	}
+	return hex.Dump(cmds)
+}
+
+func TestBuildCommands(t *testing.T) {
+	seen := map[string]bool{}
+	for _, tc := range commandCases() {
+		if seen[tc.name] {
+			t.Fatalf("重复的用例名称: %s", tc.name)
+		}
+		seen[tc.name] = true
+
+		t.Run(tc.name, func(t *testing.T) {
+			p := tc.printer
+			if p == nil {
+				p = firmwarePrinter
+			}
+			got := commandOutput(p, tc.req)
+
+			checkGolden(t, tc.name, got)
+		})
+	}
+}
+
+// 固件 CODE128 的长度字节不能溢出
+func TestCode128LengthByte(t *testing.T) {
+	for n := 250; n <= 260; n++ {
+		req := PrintRequest{BarcodeType: "CODE128", BarcodeData: strings.Repeat("a", n)}
+		if err := prepareRequest(&req); err != nil {
+			t.Fatal(err)
+		}
+		cmds, err := buildCommands(firmwarePrinter, &req)
+		if err != nil {
+			continue
+		}
+		i := strings.Index(string(cmds), "\x1D\x6B\x49")
+		if i < 0 {
+			t.Fatalf("%d个字符：没有 GS k 73 指令", n)
+		}
+		length := int(cmds[i+3])
+		if payload := cmds[i+4:]; len(payload) < length || string(payload[:2]) != "{B" {
+			t.Fatalf("%d个字符：长度字节 %d 与数据不符", n, length)
+		}
+		if length != n+2 {
+			t.Errorf("%d个字符：长度字节为 %d，应为 %d", n, length, n+2)
+		}
+	}
+}
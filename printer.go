@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Printer 已配置的打印机
+type Printer struct {
+	Name      string
+	Config    PrinterConfig
+	Transport Transport
+}
+
+// 已注册的打印机
+var (
+	printers       = map[string]*Printer{}
+	printerNames   []string
+	defaultPrinter string
+)
+
+// 根据配置创建所有打印机
+func setupPrinters(cfg *Config) error {
+	for _, pc := range cfg.Printers {
+		if _, ok := printers[pc.Name]; ok {
+			return fmt.Errorf("打印机名称重复: %s", pc.Name)
+		}
+		t, err := newTransport(pc)
+		if err != nil {
+			return fmt.Errorf("打印机 %s 配置错误: %v", pc.Name, err)
+		}
+		printers[pc.Name] = &Printer{Name: pc.Name, Config: pc, Transport: t}
+		printerNames = append(printerNames, pc.Name)
+	}
+
+	if _, ok := printers[cfg.DefaultPrinter]; !ok {
+		return fmt.Errorf("默认打印机不存在: %s", cfg.DefaultPrinter)
+	}
+	defaultPrinter = cfg.DefaultPrinter
+	return nil
+}
+
+// 按名称查找打印机，名称为空时返回默认打印机
+func getPrinter(name string) (*Printer, error) {
+	if name == "" {
+		name = defaultPrinter
+	}
+	p, ok := printers[name]
+	if !ok {
+		return nil, fmt.Errorf("打印机不存在: %s", name)
+	}
+	return p, nil
+}
+
+// 打开打印机的传输通道
+func (p *Printer) open() (Transport, error) {
+	if err := p.Transport.Open(); err != nil {
+		return nil, fmt.Errorf("无法打开打印机端口: %w", err)
+	}
+	return p.Transport, nil
+}
+
+// 同步打印时等待开始打印的超时
+func (c PrinterConfig) waitTimeout() time.Duration {
+	if c.WaitTimeout > 0 {
+		return time.Duration(c.WaitTimeout) * time.Millisecond
+	}
+	return 30 * time.Second
+}
+
+// 打印分辨率（点/英寸）
+func (c PrinterConfig) dpi() int {
+	if c.DPI > 0 {
+		return c.DPI
+	}
+	return 203
+}
+
+// 可打印宽度（点），58mm 纸打印区域为 48mm，80mm 纸为 72mm
+func (c PrinterConfig) printWidth() int {
+	printable := 72
+	switch w := c.PaperWidth; {
+	case w == 58:
+		printable = 48
+	case w > 0 && w != 80:
+		printable = w - 8
+	}
+	return int(math.Ceil(float64(printable) * float64(c.dpi()) / 25.4))
+}
+
+// 该条码类型是否必须使用光栅模式（打印机固件不支持）
+func (p *Printer) rasterOnly(barcodeType string) bool {
+	for _, t := range p.Config.RasterTypes {
+		if strings.EqualFold(t, barcodeType) {
+			return true
+		}
+	}
+	return false
+}
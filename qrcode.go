@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xiaofeiwuuu/tiaoxingma/barcode"
+)
+
+// QR码纠错等级对应的 GS ( k 参数
+var qrLevelCodes = map[string]byte{"L": 48, "M": 49, "Q": 50, "H": 51}
+
+// 校验 QR 码参数，返回纠错等级对应的参数
+func checkQRCode(req *PrintRequest) (byte, error) {
+	level := strings.ToUpper(req.QRErrorLevel)
+	if level == "" {
+		level = "M"
+	}
+	levelCode, ok := qrLevelCodes[level]
+	if !ok {
+		return 0, fmt.Errorf("QR码纠错等级必须是 L、M、Q 或 H: %s", req.QRErrorLevel)
+	}
+	if req.QRModuleSize < 1 || req.QRModuleSize > 16 {
+		return 0, fmt.Errorf("QR码模块大小必须是 1-16: %d", req.QRModuleSize)
+	}
+	if req.QRModel != 1 && req.QRModel != 2 {
+		return 0, fmt.Errorf("QR码模型必须是 1 或 2: %d", req.QRModel)
+	}
+	if len(req.BarcodeData) == 0 {
+		return 0, fmt.Errorf("QR码数据不能为空")
+	}
+	// 容量取决于纠错等级和编码模式（纯数字最多7089位，任意字节在 H 级最多1273字节）
+	// 按模型 2 计算；模型 1 的容量更小，超出时由打印机拒绝
+	qrLevel, _ := barcode.ParseQRLevel(level)
+	if required, capacity := barcode.QRCodewords(req.BarcodeData, qrLevel); required > capacity {
+		return 0, &CapacityError{BarcodeType: "QR", Required: required, Capacity: capacity}
+	}
+	return levelCode, nil
+}
+
+// 使用 GS ( k 指令打印 QR 码
+// 依次发送：选择模型(165)、模块大小(167)、纠错等级(169)、存储数据(180)、打印(181)
+func writeQRCode(printer io.Writer, req *PrintRequest) error {
+	levelCode, err := checkQRCode(req)
+	if err != nil {
+		return err
+	}
+	data := []byte(req.BarcodeData)
+
+	// GS ( k pL pH cn fn n1 n2：选择模型（49=模型1，50=模型2）
+	printer.Write([]byte{0x1D, 0x28, 0x6B, 0x04, 0x00, 0x31, 0x41, byte(48 + req.QRModel), 0x00})
+	// GS ( k pL pH cn fn n：模块大小
+	printer.Write([]byte{0x1D, 0x28, 0x6B, 0x03, 0x00, 0x31, 0x43, byte(req.QRModuleSize)})
+	// GS ( k pL pH cn fn n：纠错等级
+	printer.Write([]byte{0x1D, 0x28, 0x6B, 0x03, 0x00, 0x31, 0x45, levelCode})
+	// GS ( k pL pH cn fn m d1...dk：存储数据，长度为 k+3
+	size := len(data) + 3
+	printer.Write([]byte{0x1D, 0x28, 0x6B, byte(size), byte(size >> 8), 0x31, 0x50, 0x30})
+	printer.Write(data)
+	// GS ( k pL pH cn fn m：打印已存储的数据
+	printer.Write([]byte{0x1D, 0x28, 0x6B, 0x03, 0x00, 0x31, 0x51, 0x30})
+
+	// 固件 QR 码没有 HRI 文字，需要时在下方打印数据
+	if req.ShowText {
+		printer.Write(hriText("\n" + req.BarcodeData))
+	}
+	return nil
+}
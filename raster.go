@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/xiaofeiwuuu/tiaoxingma/barcode"
+)
+
+// 软件渲染时的静区模块数
+const (
+	rasterQuietZone = 10 // 一维条码左右
+	qrQuietZone     = 4  // QR码四周
+
+	dataMatrixQuietZone = 2 // Data Matrix 四周
+	pdf417QuietZone     = 2 // PDF417 四周
+)
+
+// 没有对应固件指令、只能以光栅图打印的条码类型
+func softwareOnly(barcodeType string) bool {
+	switch strings.ToUpper(barcodeType) {
+	case "DATAMATRIX", "GS1DATAMATRIX":
+		return true
+	}
+	return false
+}
+
+// 是否以光栅图打印：请求指定 raster、打印机固件不支持（配置 rasterTypes）、
+// 只有软件编码器（Data Matrix）或带附加码
+func useRaster(p *Printer, req *PrintRequest) bool {
+	return req.RenderMode == "raster" || p.rasterOnly(req.BarcodeType) || softwareOnly(req.BarcodeType) || req.Addon != ""
+}
+
+// 将位图转换为光栅位图指令 GS v 0 m xL xH yL yH d1...dk
+func rasterImage(bm *barcode.Bitmap) []byte {
+	widthBytes := (bm.Width + 7) / 8
+	cmd := []byte{0x1D, 0x76, 0x30, 0x00,
+		byte(widthBytes), byte(widthBytes >> 8),
+		byte(bm.Height), byte(bm.Height >> 8)}
+
+	for y := 0; y < bm.Height; y++ {
+		for xb := 0; xb < widthBytes; xb++ {
+			var b byte
+			for bit := 0; bit < 8; bit++ {
+				if bm.Get(xb*8+bit, y) {
+					b |= 0x80 >> uint(bit)
+				}
+			}
+			cmd = append(cmd, b)
+		}
+	}
+	return cmd
+}
+
+// 软件编码条码，返回光栅位图和下方显示的文字
+func renderRaster(req *PrintRequest) (*barcode.Bitmap, string, error) {
+	switch strings.ToUpper(req.BarcodeType) {
+	case "QR":
+		level, err := barcode.ParseQRLevel(req.QRErrorLevel)
+		if err != nil {
+			return nil, "", err
+		}
+		matrix, err := barcode.EncodeQR(req.BarcodeData, level)
+		if err != nil {
+			return nil, "", err
+		}
+		return matrix.Scale(req.QRModuleSize, qrQuietZone), req.BarcodeData, nil
+
+	case "DATAMATRIX", "GS1DATAMATRIX":
+		shape, err := barcode.ParseDataMatrixShape(req.DataMatrixShape)
+		if err != nil {
+			return nil, "", err
+		}
+		if req.DataMatrixModuleSize < 1 || req.DataMatrixModuleSize > 16 {
+			return nil, "", fmt.Errorf("Data Matrix模块大小必须是 1-16: %d", req.DataMatrixModuleSize)
+		}
+		gs1 := strings.ToUpper(req.BarcodeType) == "GS1DATAMATRIX"
+		matrix, err := barcode.EncodeDataMatrix(req.BarcodeData, shape, gs1)
+		if err != nil {
+			return nil, "", err
+		}
+		return matrix.Scale(req.DataMatrixModuleSize, dataMatrixQuietZone), req.BarcodeData, nil
+
+	case "PDF417":
+		// 与 GS ( k 相同：每个模块 pdf417ModuleWidth 个点，每行高度为模块宽度的 pdf417RowHeight 倍
+		level, err := checkPDF417(req)
+		if err != nil {
+			return nil, "", err
+		}
+		symbol, err := barcode.EncodePDF417(req.BarcodeData, req.PDF417Columns, req.PDF417Rows, level, req.PDF417RowHeight)
+		if err != nil {
+			return nil, "", err
+		}
+		return symbol.Scale(req.PDF417ModuleWidth, pdf417QuietZone), req.BarcodeData, nil
+
+	case "CODABAR":
+		// 宽窄比按点数取整，窄单元为 barcodeWidth 个点
+		if req.WideRatio < 2 || req.WideRatio > 3 {
+			return nil, "", fmt.Errorf("宽窄比必须是 2-3: %g", req.WideRatio)
+		}
+		narrow := rasterModuleWidth(req)
+		wide := int(math.Round(req.WideRatio * float64(narrow)))
+		if wide <= narrow {
+			wide = narrow + 1
+		}
+		code, err := barcode.EncodeCodabar(req.BarcodeData, narrow, wide)
+		if err != nil {
+			return nil, "", err
+		}
+		return code.Render(1, req.BarcodeHeight, rasterQuietZone*narrow), code.Text, nil
+
+	default:
+		code, err := barcode.Encode(req.BarcodeType, req.BarcodeData)
+		if err != nil {
+			return nil, "", err
+		}
+		if req.Addon != "" {
+			if err := code.AddAddon(req.Addon); err != nil {
+				return nil, "", err
+			}
+		}
+		return code.Render(rasterModuleWidth(req), req.BarcodeHeight, rasterQuietZone), code.Text, nil
+	}
+}
+
+// 光栅模式下每个模块的点数
+func rasterModuleWidth(req *PrintRequest) int {
+	if req.BarcodeWidth < 1 || req.BarcodeWidth > 6 {
+		return 3
+	}
+	return req.BarcodeWidth
+}
+
+// 以光栅图方式打印软件编码的条形码
+func writeRasterImage(printer io.Writer, bm *barcode.Bitmap, text string, req *PrintRequest) {
+	printer.Write(rasterImage(bm))
+
+	// 光栅图没有固件的 HRI 文字，改为在下方打印文本
+	if req.ShowText {
+		printer.Write(hriText(text))
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SequenceSpec 序列号：生成 count 个 前缀 + 数字 + 后缀 形式的条码数据，如 PKG-000120
+type SequenceSpec struct {
+	Prefix  string `json:"prefix"`  // 前缀
+	Start   int64  `json:"start"`   // 起始值，使用计数器时只在计数器不存在时生效
+	Step    int64  `json:"step"`    // 步长，默认 1，可为负数
+	Count   int    `json:"count"`   // 数量，默认 1
+	Pad     int    `json:"pad"`     // 数字补零后的位数 (0-19)，如 6 生成 000120
+	Suffix  string `json:"suffix"`  // 后缀
+	Counter string `json:"counter"` // 命名计数器：从计数器的值开始，打印后计数器前进，重启后继续
+}
+
+// SequenceResult 本次打印的序列号范围
+type SequenceResult struct {
+	First string `json:"first"`          // 第一个序列号
+	Last  string `json:"last"`           // 最后一个序列号
+	Count int    `json:"count"`          // 序列号个数
+	Next  *int64 `json:"next,omitempty"` // 计数器的下一个值（使用计数器时）
+}
+
+var counterNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// 设置默认值并检查参数
+func (s *SequenceSpec) check() error {
+	if s.Step == 0 {
+		s.Step = 1
+	}
+	if s.Count == 0 {
+		s.Count = 1
+	}
+	if s.Count < 1 || s.Count > maxBatchItems {
+		return fmt.Errorf("sequence.count 必须是 1-%d: %d", maxBatchItems, s.Count)
+	}
+	if s.Pad < 0 || s.Pad > 19 {
+		return fmt.Errorf("sequence.pad 必须是 0-19: %d", s.Pad)
+	}
+	if s.Counter != "" && !counterNamePattern.MatchString(s.Counter) {
+		return fmt.Errorf("计数器名称无效: %q（只能包含字母、数字、_、. 和 -）", s.Counter)
+	}
+	return nil
+}
+
+// 检查从 first 开始的序列号不为负数，且计数器的下一个值不超出 int64
+// 使用计数器且步长为负数时，下一个值也不能为负数，否则计数器此后无法再使用
+func (s *SequenceSpec) checkRange(first int64) error {
+	if first < 0 {
+		return fmt.Errorf("序列号不能为负数: %d", first)
+	}
+	n := int64(s.Count)
+	if s.Step > 0 && s.Step > (math.MaxInt64-first)/n {
+		return fmt.Errorf("序列号超出范围: 从 %d 开始、步长 %d 的 %d 个序列号超过 %d", first, s.Step, s.Count, int64(math.MaxInt64))
+	}
+	// first + Step*(n-1) >= 0，按除法比较避免乘法溢出
+	if s.Step < 0 && n > 1 && s.Step < -(first/(n-1)) {
+		return fmt.Errorf("序列号不能为负数: 从 %d 开始、步长 %d 的 %d 个序列号小于 0", first, s.Step, s.Count)
+	}
+	// first + Step*n >= 0
+	if s.Counter != "" && s.Step < 0 && s.Step < -(first/n) {
+		return fmt.Errorf("计数器 %s 已用尽: 从 %d 开始、步长 %d 的 %d 个序列号之后计数器将小于 0", s.Counter, first, s.Step, s.Count)
+	}
+	return nil
+}
+
+// 第 i 个序列号
+func (s *SequenceSpec) value(first int64, i int) string {
+	return fmt.Sprintf("%s%0*d%s", s.Prefix, s.Pad, first+s.Step*int64(i), s.Suffix)
+}
+
+// 最后一个序列号之后的值
+func (s *SequenceSpec) next(first int64) int64 {
+	return first + s.Step*int64(s.Count)
+}
+
+// CounterStore 命名计数器，保存各计数器的下一个值
+// 每次更新先写入文件再生效，服务重启后从文件恢复，序列号可能跳号但不会重复
+type CounterStore struct {
+	path string
+
+	mu     sync.Mutex
+	values map[string]int64
+}
+
+// 计数器，由 main 初始化
+var counters *CounterStore
+
+// 打开计数器文件，文件不存在时创建空的计数器
+func openCounterStore(path string) (*CounterStore, error) {
+	s := &CounterStore{path: path, values: map[string]int64{}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取计数器文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &s.values); err != nil {
+		return nil, fmt.Errorf("解析计数器文件 %s 失败: %v", path, err)
+	}
+	return s, nil
+}
+
+// 写入计数器文件，调用时持有 s.mu
+func (s *CounterStore) save() error {
+	data, err := json.MarshalIndent(s.values, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("写入计数器文件失败: %v", err)
+	}
+	if _, err := file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("写入计数器文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("写入计数器文件失败: %v", err)
+	}
+	return nil
+}
+
+// Peek 计数器的当前值，计数器不存在时返回 start
+func (s *CounterStore) Peek(name string, start int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.values[name]; ok {
+		return v
+	}
+	return start
+}
+
+// Use 以计数器的当前值（不存在时为 start）调用 build 生成打印内容并返回计数器的下一个值，
+// 先将下一个值写入文件保留这段序列号，再调用 submit 提交任务：
+// build 或写入文件失败时计数器不变，submit 失败时保留的序列号跳过不用
+// 调用期间持有锁，同一计数器的并发请求依次取号
+func (s *CounterStore) Use(name string, start int64, build func(first int64) (int64, error), submit func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first, exists := s.values[name]
+	if !exists {
+		first = start
+	}
+	next, err := build(first)
+	if err != nil {
+		return err
+	}
+
+	s.values[name] = next
+	if err := s.save(); err != nil {
+		if exists {
+			s.values[name] = first
+		} else {
+			delete(s.values, name)
+		}
+		return err
+	}
+	if submit == nil {
+		return nil
+	}
+	return submit()
+}
+
+// Set 设置计数器的值
+func (s *CounterStore) Set(name string, next int64) error {
+	return s.Use(name, 0, func(int64) (int64, error) { return next, nil }, nil)
+}
+
+// List 全部计数器的当前值
+func (s *CounterStore) List() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make(map[string]int64, len(s.values))
+	for name, v := range s.values {
+		list[name] = v
+	}
+	return list
+}
+
+// 计数器接口处理器
+//
+//	GET  /api/counters          全部计数器的当前值
+//	POST /api/counters/{name}   设置计数器，请求体 {"next": 500}
+func counterHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w)
+
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/counters"), "/")
+	if name == "" {
+		if r.Method != "GET" {
+			sendError(w, "仅支持GET请求")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"counters": counters.List()})
+		return
+	}
+
+	if r.Method != "POST" {
+		sendError(w, "仅支持POST请求")
+		return
+	}
+	if !counterNamePattern.MatchString(name) {
+		sendError(w, fmt.Sprintf("计数器名称无效: %q（只能包含字母、数字、_、. 和 -）", name))
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		sendError(w, "读取请求失败")
+		return
+	}
+	var req struct {
+		Next *int64 `json:"next"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		sendError(w, "解析JSON失败")
+		return
+	}
+	if req.Next == nil || *req.Next < 0 {
+		sendError(w, "next 必须是非负整数")
+		return
+	}
+	if err := counters.Set(name, *req.Next); err != nil {
+		sendError(w, err.Error())
+		return
+	}
+	sendSuccess(w, fmt.Sprintf("计数器 %s 已设置为 %d", name, *req.Next))
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSequenceValue(t *testing.T) {
+	cases := []struct {
+		spec SequenceSpec
+		want []string
+	}{
+		{SequenceSpec{Prefix: "PKG-", Start: 120, Count: 3, Pad: 6}, []string{"PKG-000120", "PKG-000121", "PKG-000122"}},
+		{SequenceSpec{Start: 10, Step: -5, Count: 3, Suffix: "/A"}, []string{"10/A", "5/A", "0/A"}},
+		{SequenceSpec{Start: 99, Step: 2, Count: 2, Pad: 2}, []string{"99", "101"}},
+	}
+	for _, tc := range cases {
+		if err := tc.spec.check(); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for i := 0; i < tc.spec.Count; i++ {
+			got = append(got, tc.spec.value(tc.spec.Start, i))
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%+v 生成 %v，应为 %v", tc.spec, got, tc.want)
+		}
+	}
+
+	ranges := []struct {
+		spec SequenceSpec
+		ok   bool
+	}{
+		{SequenceSpec{Start: 5, Step: -5, Count: 3}, false},
+		{SequenceSpec{Start: 10, Step: -5, Count: 3}, true},
+		{SequenceSpec{Start: -1}, false},
+		{SequenceSpec{Start: 1, Step: math.MinInt64, Count: 2}, false},
+		{SequenceSpec{Start: 0, Step: math.MinInt64}, true},
+		{SequenceSpec{Start: 1, Step: math.MaxInt64 / 2, Count: 3}, false}, // 乘法溢出
+		{SequenceSpec{Start: math.MaxInt64 - 3, Count: 3}, true},
+		{SequenceSpec{Start: math.MaxInt64 - 2, Count: 3}, false}, // 下一个值溢出
+		{SequenceSpec{Start: 5, Step: -1, Count: 6}, true},
+		{SequenceSpec{Start: 5, Step: -1, Count: 6, Counter: "c"}, false}, // 计数器的下一个值为 -1
+		{SequenceSpec{Start: 5, Step: -1, Count: 5, Counter: "c"}, true},
+		{SequenceSpec{Start: 0, Step: math.MinInt64, Counter: "c"}, false},
+	}
+	for _, tc := range ranges {
+		tc.spec.check()
+		if err := tc.spec.checkRange(tc.spec.Start); (err == nil) != tc.ok {
+			t.Errorf("%+v: 范围检查结果 %v", tc.spec, err)
+		}
+	}
+}
+
+// 使用计数器的序列号在重启后继续，打印失败时计数器不前进
+func TestSequenceCounter(t *testing.T) {
+	dir := t.TempDir()
+	var err error
+	if jobs, err = openJobQueue(filepath.Join(dir, "jobs.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { jobs.file.Close(); jobs = nil }()
+	if counters, err = openCounterStore(filepath.Join(dir, "counters.json")); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { counters = nil }()
+	printers[firmwarePrinter.Name] = firmwarePrinter
+	defer delete(printers, firmwarePrinter.Name)
+
+	submit := func(req PrintRequest) (*Job, *SequenceResult, error) {
+		req.Printer = firmwarePrinter.Name
+		if err := prepareRequest(&req); err != nil {
+			return nil, nil, err
+		}
+		return submitBarcode(&req)
+	}
+	spec := func() *SequenceSpec {
+		return &SequenceSpec{Prefix: "PKG-", Start: 120, Count: 3, Pad: 6, Counter: "pkg"}
+	}
+
+	_, seq, err := submit(PrintRequest{Sequence: spec(), Copies: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq.First != "PKG-000120" || seq.Last != "PKG-000122" || seq.Next == nil || *seq.Next != 123 {
+		t.Errorf("第一次打印的序列号为 %+v", seq)
+	}
+
+	// 重启后从计数器继续
+	if counters, err = openCounterStore(filepath.Join(dir, "counters.json")); err != nil {
+		t.Fatal(err)
+	}
+	job, seq, err := submit(PrintRequest{Sequence: spec(), Copies: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq.First != "PKG-000123" || seq.Last != "PKG-000125" {
+		t.Errorf("重启后的序列号为 %+v，应从 PKG-000123 开始", seq)
+	}
+	jobs.mu.Lock()
+	data := jobs.jobs[job.ID].Data
+	jobs.mu.Unlock()
+	if n := bytes.Count(data, []byte("\x1B\x40")); n != 6 {
+		t.Errorf("3个序列号各2份应有6个标签，实际%d个", n)
+	}
+
+	// 条码无效时不占用序列号
+	bad := spec()
+	bad.Prefix = "价格"
+	var de detailedError
+	if _, _, err := submit(PrintRequest{Sequence: bad}); !errors.As(err, &de) {
+		t.Errorf("序列号生成的条码无效时应返回校验错误: %v", err)
+	}
+	if next := counters.Peek("pkg", 0); next != 126 {
+		t.Errorf("打印失败后计数器为 %d，应为 126", next)
+	}
+
+	// 超出任务大小上限时不占用序列号：每个光栅 QR 码约 27KB
+	big := spec()
+	big.Count = maxBatchItems
+	var size *JobSizeError
+	if _, _, err := submit(PrintRequest{Sequence: big, BarcodeType: "QR", RenderMode: "raster", QRModuleSize: 16}); !errors.As(err, &size) {
+		t.Errorf("打印数据超出上限时应返回 JobSizeError: %v", err)
+	}
+	if next := counters.Peek("pkg", 0); next != 126 {
+		t.Errorf("任务过大时计数器为 %d，应为 126", next)
+	}
+
+	if _, _, err := submit(PrintRequest{Sequence: spec(), BarcodeData: "x"}); err == nil {
+		t.Error("同时设置 sequence 和 barcodeData 应返回错误")
+	}
+	if _, _, err := submit(PrintRequest{BarcodeData: "x", Copies: maxBatchItems + 1}); err == nil {
+		t.Error("copies 超出范围应返回错误")
+	}
+}
+
+// 先保存计数器再提交任务：提交失败时跳号，保存失败时不提交
+func TestCounterStoreUse(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openCounterStore(filepath.Join(dir, "counters.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	build := func(first int64) (int64, error) { return first + 3, nil }
+
+	failed := errors.New("提交失败")
+	if err := s.Use("pkg", 10, build, func() error { return failed }); err != failed {
+		t.Errorf("应返回提交的错误: %v", err)
+	}
+	if next := s.Peek("pkg", 0); next != 13 {
+		t.Errorf("提交失败后计数器为 %d，应跳过已保留的序列号到 13", next)
+	}
+	if s, err = openCounterStore(filepath.Join(dir, "counters.json")); err != nil {
+		t.Fatal(err)
+	}
+	if next := s.Peek("pkg", 0); next != 13 {
+		t.Errorf("重启后计数器为 %d，应为 13", next)
+	}
+
+	// 计数器文件无法写入时不提交任务
+	s.path = filepath.Join(dir, "missing", "counters.json")
+	submitted := false
+	if err := s.Use("pkg", 10, build, func() error { submitted = true; return nil }); err == nil || submitted {
+		t.Errorf("保存计数器失败时不应提交任务: %v", err)
+	}
+	if next := s.Peek("pkg", 0); next != 13 {
+		t.Errorf("保存失败后计数器为 %d，应保持 13", next)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !(linux && (386 || amd64 || arm || arm64 || riscv64 || loong64))
+
+package main
+
+import (
+	"os"
+)
+
+// 当前平台不能设置串口参数
+const serialConfigurable = false
+
+// 其他平台直接打开串口，波特率等参数沿用系统设置
+// （Windows 下可通过 mode COM1 BAUD=9600 PARITY=N DATA=8 STOP=1 预先配置）
+func openSerial(path string, s serialSettings) (*os.File, error) {
+	file, err := os.OpenFile(`\\.\`+path, os.O_WRONLY, 0)
+	if err != nil {
+		file, err = os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return nil, describeDeviceError(path, err)
+		}
+	}
+	return file, nil
+}
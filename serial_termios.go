@@ -0,0 +1,125 @@
+//go:build linux && (386 || amd64 || arm || arm64 || riscv64 || loong64)
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// syscall 包未导出的 termios 常量（以上架构取值相同）
+const (
+	termiosCBAUD   = 0x100f
+	termiosCRTSCTS = 0x80000000
+)
+
+// 当前平台可以通过 termios 设置串口参数
+const serialConfigurable = true
+
+// 波特率对应的 termios 速率，与 serialBaudRates 一致
+var baudRates = map[int]uint32{
+	1200:   syscall.B1200,
+	2400:   syscall.B2400,
+	4800:   syscall.B4800,
+	9600:   syscall.B9600,
+	19200:  syscall.B19200,
+	38400:  syscall.B38400,
+	57600:  syscall.B57600,
+	115200: syscall.B115200,
+	230400: syscall.B230400,
+	460800: syscall.B460800,
+	921600: syscall.B921600,
+}
+
+// 打开串口并通过 termios 设置参数
+// 以 O_NONBLOCK 打开，避免没有载波信号（DCD）的串口阻塞在 open；设置 CLOCAL 忽略调制解调器信号后恢复阻塞写入
+func openSerial(path string, s serialSettings) (*os.File, error) {
+	speed, ok := baudRates[s.baudRate]
+	if !ok {
+		return nil, fmt.Errorf("不支持的波特率: %d", s.baudRate)
+	}
+
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, describeDeviceError(path, &os.PathError{Op: "open", Path: path, Err: err})
+	}
+	if err := setTermios(uintptr(fd), s, speed); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("设置串口 %s 参数失败: %w", path, err)
+	}
+	if err := syscall.SetNonblock(fd, false); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("设置串口 %s 参数失败: %w", path, err)
+	}
+	return os.NewFile(uintptr(fd), path), nil
+}
+
+// 设置原始模式和串口参数
+func setTermios(fd uintptr, s serialSettings, speed uint32) error {
+	var tio syscall.Termios
+	if err := ioctlTermios(fd, syscall.TCGETS, &tio); err != nil {
+		return err
+	}
+
+	// 原始模式（等同 cfmakeraw）
+	tio.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON | syscall.IXOFF | syscall.IXANY
+	tio.Oflag &^= syscall.OPOST
+	tio.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	tio.Cflag &^= syscall.CSIZE | syscall.PARENB | syscall.PARODD | syscall.CSTOPB |
+		termiosCRTSCTS | termiosCBAUD
+	tio.Cflag |= syscall.CLOCAL | syscall.CREAD
+
+	// 波特率
+	tio.Cflag |= speed
+	tio.Ispeed = speed
+	tio.Ospeed = speed
+
+	// 数据位
+	switch s.dataBits {
+	case 5:
+		tio.Cflag |= syscall.CS5
+	case 6:
+		tio.Cflag |= syscall.CS6
+	case 7:
+		tio.Cflag |= syscall.CS7
+	default:
+		tio.Cflag |= syscall.CS8
+	}
+
+	// 校验位
+	switch s.parity {
+	case "odd":
+		tio.Cflag |= syscall.PARENB | syscall.PARODD
+	case "even":
+		tio.Cflag |= syscall.PARENB
+	}
+
+	// 停止位
+	if s.stopBits == 2 {
+		tio.Cflag |= syscall.CSTOPB
+	}
+
+	// 流控
+	switch s.flowControl {
+	case "rtscts":
+		tio.Cflag |= termiosCRTSCTS
+	case "xonxoff":
+		tio.Iflag |= syscall.IXON | syscall.IXOFF
+	}
+
+	tio.Cc[syscall.VMIN] = 1
+	tio.Cc[syscall.VTIME] = 0
+
+	return ioctlTermios(fd, syscall.TCSETS, &tio)
+}
+
+func ioctlTermios(fd uintptr, req uintptr, tio *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(tio)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+//go:build linux && (386 || amd64 || arm || arm64 || riscv64 || loong64)
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+// 打开伪终端，返回主设备和从设备路径
+func openPTY(t *testing.T) (*os.File, string) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("无法打开伪终端: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+
+	var unlock, n uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		t.Skipf("解锁伪终端失败: %v", errno)
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+		t.Skipf("获取伪终端编号失败: %v", errno)
+	}
+	path := fmt.Sprintf("/dev/pts/%d", n)
+	if _, err := os.Stat(path); err != nil {
+		t.Skipf("伪终端从设备不可用: %v", err)
+	}
+	return master, path
+}
+
+// 打开串口后读回 termios，检查参数已按配置设置
+func TestOpenSerialTermios(t *testing.T) {
+	cases := []struct {
+		cfg             PrinterConfig
+		speed           uint32
+		set, clear      uint32 // Cflag 中应设置和应清除的位
+		iflagSet, iflag uint32 // Iflag 中应设置和应清除的位
+	}{
+		{
+			cfg:   PrinterConfig{},
+			speed: syscall.B9600,
+			set:   syscall.CS8 | syscall.CLOCAL | syscall.CREAD,
+			clear: syscall.PARENB | syscall.CSTOPB | termiosCRTSCTS,
+			iflag: syscall.IXON | syscall.IXOFF | syscall.ICRNL,
+		},
+		{
+			cfg:   PrinterConfig{BaudRate: 115200, DataBits: 7, Parity: "odd", StopBits: 2, FlowControl: "rtscts"},
+			speed: syscall.B115200,
+			set:   syscall.CS7 | syscall.PARENB | syscall.PARODD | syscall.CSTOPB | termiosCRTSCTS,
+			iflag: syscall.IXON | syscall.IXOFF,
+		},
+		{
+			cfg:      PrinterConfig{BaudRate: 19200, DataBits: 5, Parity: "even", FlowControl: "xonxoff"},
+			speed:    syscall.B19200,
+			set:      syscall.CS5 | syscall.PARENB,
+			clear:    syscall.PARODD | syscall.CSTOPB | termiosCRTSCTS,
+			iflagSet: syscall.IXON | syscall.IXOFF,
+		},
+	}
+	for _, tc := range cases {
+		master, path := openPTY(t)
+		tc.cfg.Path = path
+		tr, err := newSerialTransport(tc.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tr.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		// 设置参数后恢复阻塞写入（File.Fd 会把文件设为阻塞模式，这里通过 SyscallConn 读取）
+		var flags uintptr
+		var errno syscall.Errno
+		if conn, err := tr.file.SyscallConn(); err == nil {
+			conn.Control(func(fd uintptr) {
+				flags, _, errno = syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFL, 0)
+			})
+		}
+		if errno != 0 || flags&syscall.O_NONBLOCK != 0 {
+			t.Errorf("打开后应清除 O_NONBLOCK: flags=%#x, %v", flags, errno)
+		}
+
+		var tio syscall.Termios
+		if err := ioctlTermios(tr.file.Fd(), syscall.TCGETS, &tio); err != nil {
+			t.Fatal(err)
+		}
+		name := tr.Status().Address
+		if tio.Cflag&termiosCBAUD != tc.speed {
+			t.Errorf("%s: 波特率为 %#x，应为 %#x", name, tio.Cflag&termiosCBAUD, tc.speed)
+		}
+		// 伪终端驱动总是设为 CS8 且清除 PARENB，数据位和是否校验无法读回
+		set, clear := tc.set&^(syscall.CSIZE|syscall.PARENB), tc.clear&^syscall.PARENB
+		if tio.Cflag&set != set || tio.Cflag&clear != 0 {
+			t.Errorf("%s: Cflag 为 %#x，应设置 %#x、清除 %#x", name, tio.Cflag, set, clear)
+		}
+		if tio.Iflag&tc.iflagSet != tc.iflagSet || tio.Iflag&tc.iflag != 0 {
+			t.Errorf("%s: Iflag 为 %#x，应设置 %#x、清除 %#x", name, tio.Iflag, tc.iflagSet, tc.iflag)
+		}
+		if tio.Lflag&(syscall.ICANON|syscall.ECHO) != 0 || tio.Oflag&syscall.OPOST != 0 {
+			t.Errorf("%s: 应为原始模式: Lflag=%#x Oflag=%#x", name, tio.Lflag, tio.Oflag)
+		}
+
+		// 原始模式下数据原样到达
+		data := "\x1B\x40\n\r\x11\x13"
+		if _, err := tr.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 16)
+		n, err := master.Read(buf)
+		if err != nil || string(buf[:n]) != data {
+			t.Errorf("%s: 伪终端收到 %q, %v", name, buf[:n], err)
+		}
+		tr.Close()
+	}
+}
+
+func TestOpenSerialErrors(t *testing.T) {
+	// 配置允许的波特率都有对应的 termios 速率
+	for _, baud := range serialBaudRates {
+		if _, ok := baudRates[baud]; !ok {
+			t.Errorf("波特率 %d 没有对应的 termios 速率", baud)
+		}
+	}
+
+	// 普通文件不是终端，无法设置参数
+	file, err := os.CreateTemp(t.TempDir(), "tty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	tr, _ := newSerialTransport(PrinterConfig{Path: file.Name()})
+	if err := tr.Open(); err == nil || !strings.Contains(err.Error(), "设置串口") {
+		t.Errorf("普通文件应返回设置参数失败: %v", err)
+	}
+}
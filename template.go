@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/xiaofeiwuuu/tiaoxingma/barcode"
+)
+
+// 模板目录，由配置 templateDir 设置
+var templateDir = "templates"
+
+// 模板图片的像素上限，解码前按文件头中的尺寸检查，避免为超大图片分配内存
+const maxTemplateImagePixels = 4096 * 4096
+
+// Template 标签模板，元素自上而下依次打印
+type Template struct {
+	Printer  string            `json:"printer,omitempty"` // 默认打印机，请求中的 printer 优先
+	Cut      bool              `json:"cut"`               // 打印后是否切纸
+	Elements []TemplateElement `json:"elements"`
+}
+
+// TemplateElement 模板元素
+type TemplateElement struct {
+	Type  string `json:"type"`  // 元素类型：text, barcode, qr, line, image, spacer
+	Align string `json:"align"` // 对齐方式：left（默认）, center, right
+
+	// 文字（type=text），可包含 {{变量}}
+	Text      string `json:"text"`
+	Size      int    `json:"size"`      // 字号倍数 (1-8)，默认 1
+	Bold      bool   `json:"bold"`      // 加粗
+	Underline bool   `json:"underline"` // 下划线
+
+	// 条码和二维码（type=barcode, qr），参数与打印接口相同，barcodeData、addon 和 gs1Data 可包含 {{变量}}
+	// 对齐方式使用 align，center、cut、printer、copies、sequence 不起作用
+	PrintRequest
+
+	// 图片（type=image）
+	Image string `json:"image"` // 模板目录下的 PNG、JPEG 或 GIF 文件
+	Width int    `json:"width"` // 图片或分隔线的宽度（点），0 为原始宽度或整行
+
+	Thickness int `json:"thickness"` // 分隔线粗细（点），默认 2
+	Lines     int `json:"lines"`     // 空白行数（type=spacer），默认 1
+}
+
+// TemplatePrintRequest 模板打印请求
+type TemplatePrintRequest struct {
+	Template string                 `json:"template"` // 模板名称，即模板目录下不含 .json 的文件名
+	Data     map[string]interface{} `json:"data"`     // 模板变量
+	Printer  string                 `json:"printer"`  // 打印机名称，为空时使用模板指定的或默认打印机
+}
+
+var (
+	templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	templateVarPattern  = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+	templateAligns      = map[string]byte{"": 0, "left": 0, "center": 1, "right": 2}
+)
+
+// 模板打印处理器
+func templatePrintHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w)
+
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	if r.Method != "POST" {
+		sendError(w, "仅支持POST请求")
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		sendError(w, "读取请求失败")
+		return
+	}
+	var req TemplatePrintRequest
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // 保留数字原样，如 6901234567892
+	if err := dec.Decode(&req); err != nil {
+		sendError(w, "解析JSON失败")
+		return
+	}
+
+	t, err := loadTemplate(req.Template)
+	if err != nil {
+		sendError(w, err.Error())
+		return
+	}
+	name := req.Printer
+	if name == "" {
+		name = t.Printer
+	}
+	p, err := getPrinter(name)
+	if err != nil {
+		sendError(w, err.Error())
+		return
+	}
+
+	cmds, err := buildTemplateCommands(p, t, req.Data)
+	if err != nil {
+		sendPrintError(w, err)
+		return
+	}
+	job, err := jobs.Submit(p.Name, "模板 "+req.Template, cmds)
+	if err != nil {
+		sendPrintError(w, err)
+		return
+	}
+	sendJobResponse(w, r, job, PrintResponse{})
+}
+
+// 读取模板文件，每次打印时重新读取，修改模板无需重启服务
+func loadTemplate(name string) (*Template, error) {
+	if !templateNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("模板名称无效: %q（只能包含字母、数字、_ 和 -）", name)
+	}
+	data, err := os.ReadFile(filepath.Join(templateDir, name+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("模板不存在: %s", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取模板 %s 失败: %v", name, err)
+	}
+
+	t := &Template{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("解析模板 %s 失败: %v", name, err)
+	}
+	if len(t.Elements) == 0 {
+		return nil, fmt.Errorf("模板 %s 没有元素", name)
+	}
+	return t, nil
+}
+
+// 生成模板标签的 ESC/POS 指令
+func buildTemplateCommands(p *Printer, t *Template, data map[string]interface{}) ([]byte, error) {
+	vars := map[string]string{}
+	for k, v := range data {
+		if v != nil {
+			vars[k] = fmt.Sprint(v)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	// 初始化打印机 (ESC @)
+	buf.Write([]byte("\x1B\x40"))
+
+	for i, e := range t.Elements {
+		if err := writeTemplateElement(&buf, p, e, vars); err != nil {
+			return nil, fmt.Errorf("模板第%d个元素（%s）: %w", i+1, e.Type, err)
+		}
+	}
+
+	// 恢复左对齐，走纸后切纸
+	buf.Write([]byte("\x1B\x61\x00"))
+	buf.Write([]byte("\n\n\n"))
+	if t.Cut {
+		buf.Write([]byte("\n\n\n\n"))
+		buf.Write([]byte{0x1D, 0x56, 0x00})
+	}
+	return buf.Bytes(), nil
+}
+
+// 替换 {{变量}}，变量未提供时报错
+func expandTemplate(s string, vars map[string]string) (string, error) {
+	var missing string
+	out := templateVarPattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := templateVarPattern.FindStringSubmatch(m)[1]
+		v, ok := vars[name]
+		if !ok && missing == "" {
+			missing = name
+		}
+		return v
+	})
+	if missing != "" {
+		return "", fmt.Errorf("缺少模板变量: %s", missing)
+	}
+	return out, nil
+}
+
+// 文字中的变量值不能包含换行以外的控制字符，否则 ESC、GS 等字节会被打印机当作指令执行
+func checkTextVars(s string, vars map[string]string) error {
+	for _, m := range templateVarPattern.FindAllStringSubmatch(s, -1) {
+		v := vars[m[1]]
+		for i := 0; i < len(v); i++ {
+			if c := v[i]; (c < 0x20 && c != '\n') || c == 0x7F {
+				return fmt.Errorf("模板变量 %s 第%d个字节是控制字符 0x%02X，文字中只允许换行", m[1], i+1, c)
+			}
+		}
+	}
+	return nil
+}
+
+// 写入一个模板元素，每个元素从行首开始，结束后回到行首
+func writeTemplateElement(buf *bytes.Buffer, p *Printer, e TemplateElement, vars map[string]string) error {
+	align, ok := templateAligns[strings.ToLower(e.Align)]
+	if !ok {
+		return fmt.Errorf("align 必须是 left、center 或 right: %s", e.Align)
+	}
+	// ESC a n 对齐方式
+	buf.Write([]byte{0x1B, 0x61, align})
+
+	switch strings.ToLower(e.Type) {
+	case "text":
+		text, err := expandTemplate(e.Text, vars)
+		if err != nil {
+			return err
+		}
+		if err := checkTextVars(e.Text, vars); err != nil {
+			return err
+		}
+		size := e.Size
+		if size == 0 {
+			size = 1
+		}
+		if size < 1 || size > 8 {
+			return fmt.Errorf("字号必须是 1-8: %d", size)
+		}
+		// GS ! n 字符放大倍数（高 4 位为宽度，低 4 位为高度）
+		buf.Write([]byte{0x1D, 0x21, byte((size-1)<<4 | (size - 1))})
+		if e.Bold {
+			buf.Write([]byte{0x1B, 0x45, 0x01}) // ESC E 加粗
+		}
+		if e.Underline {
+			buf.Write([]byte{0x1B, 0x2D, 0x01}) // ESC - 下划线
+		}
+		// 文字按 UTF-8 原样发送，打印中文需将打印机设置为 UTF-8 代码页
+		buf.WriteString(text + "\n")
+		buf.Write([]byte{0x1D, 0x21, 0x00, 0x1B, 0x45, 0x00, 0x1B, 0x2D, 0x00})
+
+	case "barcode", "qr":
+		req := e.PrintRequest
+		if strings.ToLower(e.Type) == "qr" {
+			req.BarcodeType = "QR"
+		}
+		var err error
+		if req.BarcodeData, err = expandTemplate(req.BarcodeData, vars); err != nil {
+			return err
+		}
+		if req.Addon, err = expandTemplate(req.Addon, vars); err != nil {
+			return err
+		}
+		if len(e.GS1Data) > 0 {
+			req.GS1Data = map[string]string{}
+			for ai, value := range e.GS1Data {
+				if req.GS1Data[ai], err = expandTemplate(value, vars); err != nil {
+					return err
+				}
+			}
+		}
+		if err := prepareRequest(&req); err != nil {
+			return err
+		}
+		writeBarcodeSettings(buf, &req)
+		if err := writeBarcode(buf, p, &req); err != nil {
+			return err
+		}
+		buf.Write([]byte("\n"))
+
+	case "line":
+		width := e.Width
+		if width == 0 {
+			width = p.Config.printWidth()
+		}
+		thickness := e.Thickness
+		if thickness == 0 {
+			thickness = 2
+		}
+		if width < 1 || thickness < 1 || thickness > 255 {
+			return fmt.Errorf("分隔线宽度必须大于0，粗细必须是 1-255")
+		}
+		if width > p.Config.printWidth() {
+			return &WidthError{Width: width, PrintWidth: p.Config.printWidth()}
+		}
+		line := barcode.NewBitmap(width, thickness)
+		line.FillRect(0, 0, width, thickness, true)
+		buf.Write(rasterImage(line))
+
+	case "image":
+		bm, err := loadTemplateImage(e.Image, e.Width, p.Config.printWidth())
+		if err != nil {
+			return err
+		}
+		buf.Write(rasterImage(bm))
+
+	case "spacer":
+		lines := e.Lines
+		if lines == 0 {
+			lines = 1
+		}
+		if lines < 1 || lines > 50 {
+			return fmt.Errorf("空白行数必须是 1-50: %d", lines)
+		}
+		buf.WriteString(strings.Repeat("\n", lines))
+
+	default:
+		return fmt.Errorf("不支持的元素类型: %s（可用 text、barcode、qr、line、image、spacer）", e.Type)
+	}
+	return nil
+}
+
+// 读取模板目录下的图片并转换为黑白位图，width 大于 0 时按宽度等比缩放
+// 原图像素超出上限、缩放后的宽度超出 printWidth 或高度超出光栅指令的上限时，在解码图片之前返回错误
+func loadTemplateImage(name string, width, printWidth int) (*barcode.Bitmap, error) {
+	if name == "" {
+		return nil, fmt.Errorf("图片元素必须设置 image")
+	}
+	// 只允许模板目录内的文件
+	path := filepath.Join(templateDir, filepath.Clean("/"+name))
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开图片 %s 失败: %v", name, err)
+	}
+	defer file.Close()
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("解析图片 %s 失败: %v", name, err)
+	}
+	srcW, srcH := config.Width, config.Height
+	if srcW < 1 || srcH < 1 {
+		return nil, fmt.Errorf("图片 %s 尺寸无效: %dx%d", name, srcW, srcH)
+	}
+	if srcW > maxTemplateImagePixels/srcH {
+		return nil, fmt.Errorf("图片 %s 尺寸%dx%d超出上限%d像素", name, srcW, srcH, maxTemplateImagePixels)
+	}
+	if width <= 0 {
+		width = srcW
+	}
+	if width > printWidth {
+		return nil, &WidthError{Width: width, PrintWidth: printWidth}
+	}
+	height := (srcH*width + srcW/2) / srcW
+	if height < 1 {
+		height = 1
+	}
+	// GS v 0 的高度为两个字节
+	if height > 0xFFFF {
+		return nil, fmt.Errorf("图片 %s 缩放后高度%d点超出上限65535点", name, height)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("读取图片 %s 失败: %v", name, err)
+	}
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("解析图片 %s 失败: %v", name, err)
+	}
+	bounds := img.Bounds()
+
+	// 最近邻缩放，亮度低于一半为黑色，透明像素为白色
+	bm := barcode.NewBitmap(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, a := img.At(bounds.Min.X+x*srcW/width, bounds.Min.Y+y*srcH/height).RGBA()
+			lum := (299*r + 587*g + 114*b) / 1000
+			if a > 0x7FFF && 2*lum < a {
+				bm.Set(x, y, true)
+			}
+		}
+	}
+	return bm, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildTemplateCommands(t *testing.T) {
+	templateDir = filepath.Join("testdata", "templates")
+	defer func() { templateDir = "templates" }()
+
+	labelData := map[string]interface{}{
+		"name": "Green Tea", "price": "3.50", "sku": "000123",
+		"gtin": "09501101530003", "lot": "L42",
+	}
+	cases := []struct {
+		name     string
+		template string
+		data     map[string]interface{}
+	}{
+		{name: "template-label", template: "label", data: labelData},
+		{name: "template-error-missing-var", template: "label", data: map[string]interface{}{"name": "Tea"}},
+		{name: "template-error-control-char", template: "label", data: map[string]interface{}{"name": "Tea\x1B@\x1DV\x00", "price": "3.50", "sku": "000123", "gtin": "09501101530003", "lot": "L42"}},
+		{name: "template-error-del-char", template: "label", data: map[string]interface{}{"name": "Tea\x7F", "price": "3.50", "sku": "000123", "gtin": "09501101530003", "lot": "L42"}},
+		{name: "template-qr-control-char", template: "qr-text", data: map[string]interface{}{"data": "A\x1B@B\x1DV\x00"}},
+		{name: "template-error-element", template: "bad-element"},
+		{name: "template-error-barcode", template: "bad-barcode", data: map[string]interface{}{"ean": "12345"}},
+		{name: "template-error-image-width", template: "wide-image"},
+		{name: "template-error-image-pixels", template: "huge-image"},
+		{name: "template-error-not-found", template: "missing"},
+		{name: "template-error-name", template: "../label"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			tmpl, err := loadTemplate(tc.template)
+			if err == nil {
+				var cmds []byte
+				cmds, err = buildTemplateCommands(firmwarePrinter, tmpl, tc.data)
+				got = hex.Dump(cmds)
+			}
+			if err != nil {
+				got = "error: " + err.Error() + "\n"
+			}
+
+			checkGolden(t, tc.name, got)
+		})
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	vars := map[string]string{"a": "1", "b.c": "x"}
+	for in, want := range map[string]string{
+		"":                "",
+		"plain":           "plain",
+		"{{a}}-{{ b.c }}": "1-x",
+		"{a} {{a}}}":      "{a} 1}",
+	} {
+		got, err := expandTemplate(in, vars)
+		if err != nil || got != want {
+			t.Errorf("expandTemplate(%q) = %q, %v，应为 %q", in, got, err, want)
+		}
+	}
+	if _, err := expandTemplate("{{missing}}", vars); err == nil {
+		t.Error("缺少变量时应返回错误")
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Transport 打印机传输通道
+// 每个打印任务按 Open -> Write -> Close 的顺序使用
+type Transport interface {
+	Open() error
+	Write(p []byte) (int, error)
+	Close() error
+	Status() TransportStatus
+}
+
+// TransportStatus 传输通道状态
+type TransportStatus struct {
+	Type      string `json:"type"`                // 传输类型
+	Address   string `json:"address"`             // 端口、设备路径或网络地址
+	Connected bool   `json:"connected"`           // 当前是否已打开
+	LastError string `json:"lastError,omitempty"` // 最近一次错误
+	Pending   int    `json:"pending"`             // 等待打印的任务数，由状态接口填写
+}
+
+// transportState 传输通道的连接状态和最近一次错误
+// 使用独立的锁，Status 不会等待正在进行的 Open、Write（如连接超时、打印机缓冲区已满）
+type transportState struct {
+	mu        sync.Mutex
+	connected bool
+	lastErr   error
+}
+
+// 打开成功，清除之前的错误
+func (s *transportState) opened() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected, s.lastErr = true, nil
+}
+
+func (s *transportState) closed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected = false
+}
+
+func (s *transportState) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+}
+
+func (s *transportState) status(typ, address string) TransportStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := TransportStatus{Type: typ, Address: address, Connected: s.connected}
+	if s.lastErr != nil {
+		status.LastError = s.lastErr.Error()
+	}
+	return status
+}
+
+// transportError 附带明确提示的传输错误，保留原始错误用于判断是否可以重试
+type transportError struct {
+	msg string
+	err error
+}
+
+func (e *transportError) Error() string { return e.msg }
+func (e *transportError) Unwrap() error { return e.err }
+
+// partialWriteError 已向打印机发送部分数据后写入失败
+// 打印机可能已打印收到的部分，重新发送整个任务会重复打印，因此不自动重试
+type partialWriteError struct {
+	sent, total int
+	err         error
+}
+
+func (e *partialWriteError) Error() string {
+	return fmt.Sprintf("写入打印机失败（已发送%d/%d字节，打印机可能已打印部分内容）: %v", e.sent, e.total, e.err)
+}
+func (e *partialWriteError) Unwrap() error { return e.err }
+
+// 可以重试的传输错误：打印机忙、超时、连接中断、设备暂时不存在（如 USB 打印机被拔出）
+var transientErrors = []error{
+	os.ErrNotExist, os.ErrDeadlineExceeded, io.EOF, net.ErrClosed,
+	syscall.EBUSY, syscall.EAGAIN, syscall.ETIMEDOUT, syscall.EIO, syscall.ENODEV, syscall.ENXIO,
+	syscall.ECONNREFUSED, syscall.ECONNRESET, syscall.ECONNABORTED, syscall.EPIPE,
+	syscall.EHOSTUNREACH, syscall.ENETUNREACH,
+}
+
+// 判断打印失败后是否重试
+// 配置错误（地址无效等）和权限错误重试也无法恢复，直接失败
+func retryable(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	for _, target := range transientErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// 根据配置创建传输通道
+func newTransport(cfg PrinterConfig) (Transport, error) {
+	switch strings.ToLower(cfg.Type) {
+	case "", "lpt":
+		return newLPTTransport(cfg), nil
+	case "tcp", "network":
+		return newTCPTransport(cfg)
+	case "device", "usb", "file":
+		return newDeviceTransport(cfg)
+	case "serial", "com":
+		return newSerialTransport(cfg)
+	case "virtual":
+		return newVirtualTransport(cfg)
+	default:
+		return nil, fmt.Errorf("不支持的打印机类型: %s", cfg.Type)
+	}
+}
+
+// lptTransport Windows 并口（LPT）传输通道
+type lptTransport struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+
+	state transportState // 状态使用独立的锁
+}
+
+func newLPTTransport(cfg PrinterConfig) *lptTransport {
+	path := cfg.Path
+	if path == "" {
+		path = "LPT1"
+	}
+	return &lptTransport{path: path}
+}
+
+func (t *lptTransport) Open() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 优先使用设备命名空间格式，失败后尝试直接打开
+	file, err := os.OpenFile(`\\.\`+t.path, os.O_WRONLY, 0644)
+	if err != nil {
+		file, err = os.OpenFile(t.path, os.O_WRONLY, 0644)
+		if err != nil {
+			t.state.fail(err)
+			return err
+		}
+	}
+	t.file = file
+	t.state.opened()
+	return nil
+}
+
+func (t *lptTransport) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.file == nil {
+		return 0, fmt.Errorf("端口 %s 未打开", t.path)
+	}
+	n, err := t.file.Write(p)
+	if err != nil {
+		t.state.fail(err)
+	}
+	return n, err
+}
+
+func (t *lptTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.file == nil {
+		return nil
+	}
+	err := t.file.Close()
+	t.file = nil
+	t.state.closed()
+	return err
+}
+
+func (t *lptTransport) Status() TransportStatus {
+	return t.state.status("lpt", t.path)
+}
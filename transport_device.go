@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// deviceTransport 设备文件传输通道（Linux /dev/usb/lp*、/dev/lp* 等）
+type deviceTransport struct {
+	path        string
+	lockTimeout time.Duration
+
+	mu   sync.Mutex
+	file *os.File
+
+	state transportState // 状态使用独立的锁
+}
+
+func newDeviceTransport(cfg PrinterConfig) (*deviceTransport, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("设备打印机必须配置 path，如 /dev/usb/lp0")
+	}
+	t := &deviceTransport{
+		path:        cfg.Path,
+		lockTimeout: time.Duration(cfg.LockTimeout) * time.Millisecond,
+	}
+	if t.lockTimeout <= 0 {
+		t.lockTimeout = 5 * time.Second
+	}
+	return t, nil
+}
+
+func (t *deviceTransport) Open() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 不使用 O_CREATE，设备不存在时直接报错而不是创建普通文件
+	file, err := os.OpenFile(t.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		err = describeDeviceError(t.path, err)
+		t.state.fail(err)
+		return err
+	}
+
+	// 加排他锁，防止多个任务（包括其他进程）交错写入
+	if err := lockDevice(file, t.lockTimeout); err != nil {
+		file.Close()
+		t.state.fail(err)
+		return err
+	}
+
+	t.file = file
+	t.state.opened()
+	return nil
+}
+
+func (t *deviceTransport) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.file == nil {
+		return 0, fmt.Errorf("设备 %s 未打开", t.path)
+	}
+	n, err := t.file.Write(p)
+	if err != nil {
+		t.state.fail(err)
+	}
+	return n, err
+}
+
+func (t *deviceTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.file == nil {
+		return nil
+	}
+	// 关闭文件时锁随之释放
+	err := t.file.Close()
+	t.file = nil
+	t.state.closed()
+	return err
+}
+
+func (t *deviceTransport) Status() TransportStatus {
+	return t.state.status("device", t.path)
+}
+
+// 将打开设备的错误转换为明确的提示
+func describeDeviceError(path string, err error) error {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return &transportError{fmt.Sprintf("设备不存在: %s（请检查打印机是否连接）", path), err}
+	case errors.Is(err, os.ErrPermission):
+		return &transportError{fmt.Sprintf("没有权限访问设备: %s（请将运行用户加入 lp 组或调整设备权限）", path), err}
+	default:
+		return fmt.Errorf("打开设备 %s 失败: %w", path, err)
+	}
+}
@@ -0,0 +1,150 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDeviceTransport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lp0")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := newDeviceTransport(PrinterConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 每个任务追加写入
+	for _, data := range []string{"first\n", "second\n"} {
+		if err := tr.Open(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tr.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if err := tr.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := os.ReadFile(path); string(got) != "first\nsecond\n" {
+		t.Errorf("设备收到 %q", got)
+	}
+	if _, err := tr.Write([]byte("x")); err == nil {
+		t.Error("未打开时写入应返回错误")
+	}
+}
+
+// 设备不存在时不创建文件，错误可以重试（打印机可能被拔出）
+func TestDeviceTransportMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lp9")
+	tr, err := newDeviceTransport(PrinterConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tr.Open()
+	if err == nil || !strings.Contains(err.Error(), "设备不存在") {
+		t.Fatalf("设备不存在时应返回明确的错误: %v", err)
+	}
+	if !errors.Is(err, os.ErrNotExist) || !retryable(err) {
+		t.Errorf("设备不存在应保留原始错误并可以重试: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("打开不存在的设备不应创建文件")
+	}
+	if status := tr.Status(); status.LastError == "" {
+		t.Errorf("状态应记录最近一次错误: %+v", status)
+	}
+}
+
+// 没有权限时返回明确的提示，错误不重试
+func TestDeviceTransportPermission(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lp0")
+	err := describeDeviceError(path, &os.PathError{Op: "open", Path: path, Err: syscall.EACCES})
+	if !strings.Contains(err.Error(), "没有权限访问设备") || !errors.Is(err, os.ErrPermission) || retryable(err) {
+		t.Errorf("权限错误: %v", err)
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("root 不受文件权限限制")
+	}
+	if err := os.WriteFile(path, nil, 0400); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := newDeviceTransport(PrinterConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Open(); err == nil || !strings.Contains(err.Error(), "没有权限访问设备") {
+		t.Errorf("只读设备应返回权限错误: %v", err)
+	}
+}
+
+// 其他任务持有设备锁时等待，超时后返回设备被占用
+func TestDeviceTransportLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lp0")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := newDeviceTransport(PrinterConfig{Path: path})
+	b, _ := newDeviceTransport(PrinterConfig{Path: path, LockTimeout: 100})
+
+	if err := a.Open(); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	err := b.Open()
+	if err == nil || !strings.Contains(err.Error(), "正被其他任务占用") {
+		t.Fatalf("设备被锁定时应返回占用错误: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("应等待 lockTimeout 后再返回，实际等待 %v", elapsed)
+	}
+	if !retryable(err) {
+		t.Errorf("设备被占用应可以重试: %v", err)
+	}
+
+	// 释放锁后可以打开
+	time.AfterFunc(50*time.Millisecond, func() { a.Close() })
+	b.lockTimeout = 5 * time.Second
+	if err := b.Open(); err != nil {
+		t.Fatalf("锁释放后应能打开设备: %v", err)
+	}
+	b.Close()
+}
+
+// FIFO 模拟字符设备：写入的数据按顺序到达读端
+func TestDeviceTransportFIFO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lp0")
+	if err := syscall.Mkfifo(path, 0644); err != nil {
+		t.Skipf("无法创建 FIFO: %v", err)
+	}
+	// 以读写方式打开读端，避免打开写端时阻塞
+	reader, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	tr, _ := newDeviceTransport(PrinterConfig{Path: path})
+	if err := tr.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.Write([]byte("\x1B\x40fifo")); err != nil {
+		t.Fatal(err)
+	}
+	tr.Close()
+
+	buf := make([]byte, 16)
+	n, err := reader.Read(buf)
+	if err != nil || string(buf[:n]) != "\x1B\x40fifo" {
+		t.Errorf("FIFO 收到 %q, %v", buf[:n], err)
+	}
+}
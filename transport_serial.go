@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// serialSettings 串口参数
+type serialSettings struct {
+	baudRate    int
+	dataBits    int
+	parity      string // none, odd, even
+	stopBits    int
+	flowControl string // none, rtscts, xonxoff
+}
+
+// 支持的标准波特率
+var serialBaudRates = []int{1200, 2400, 4800, 9600, 19200, 38400, 57600, 115200, 230400, 460800, 921600}
+
+// 默认串口参数 9600 8N1，无流控
+var defaultSerialSettings = serialSettings{baudRate: 9600, dataBits: 8, parity: "none", stopBits: 1, flowControl: "none"}
+
+// 串口参数的简写，如 9600 8N1 none
+func (s serialSettings) String() string {
+	return fmt.Sprintf("%d %d%s%d %s", s.baudRate, s.dataBits, strings.ToUpper(s.parity[:1]), s.stopBits, s.flowControl)
+}
+
+// serialTransport 串口传输通道（RS-232 / 虚拟 COM）
+type serialTransport struct {
+	path     string
+	settings serialSettings
+
+	mu   sync.Mutex
+	file *os.File
+
+	state transportState // 状态使用独立的锁
+}
+
+func newSerialTransport(cfg PrinterConfig) (*serialTransport, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("串口打印机必须配置 path，如 /dev/ttyS0 或 COM1")
+	}
+
+	s := serialSettings{
+		baudRate:    cfg.BaudRate,
+		dataBits:    cfg.DataBits,
+		parity:      strings.ToLower(cfg.Parity),
+		stopBits:    cfg.StopBits,
+		flowControl: strings.ToLower(cfg.FlowControl),
+	}
+	// 设置默认值（9600 8N1，无流控）
+	if s.baudRate == 0 {
+		s.baudRate = 9600
+	}
+	if s.dataBits == 0 {
+		s.dataBits = 8
+	}
+	if s.parity == "" {
+		s.parity = "none"
+	}
+	if s.stopBits == 0 {
+		s.stopBits = 1
+	}
+	if s.flowControl == "" {
+		s.flowControl = "none"
+	}
+
+	// 校验参数
+	if !slices.Contains(serialBaudRates, s.baudRate) {
+		return nil, fmt.Errorf("不支持的波特率: %d（支持 %v）", s.baudRate, serialBaudRates)
+	}
+	if s.dataBits < 5 || s.dataBits > 8 {
+		return nil, fmt.Errorf("数据位必须是 5-8: %d", s.dataBits)
+	}
+	if s.parity != "none" && s.parity != "odd" && s.parity != "even" {
+		return nil, fmt.Errorf("校验位必须是 none、odd 或 even: %s", cfg.Parity)
+	}
+	if s.stopBits != 1 && s.stopBits != 2 {
+		return nil, fmt.Errorf("停止位必须是 1 或 2: %d", s.stopBits)
+	}
+	if s.flowControl != "none" && s.flowControl != "rtscts" && s.flowControl != "xonxoff" {
+		return nil, fmt.Errorf("流控必须是 none、rtscts 或 xonxoff: %s", cfg.FlowControl)
+	}
+
+	// 不能设置串口参数的平台上沿用系统设置，配置的参数不生效
+	if !serialConfigurable && s != defaultSerialSettings {
+		log.Printf("警告: 当前平台（%s/%s）无法设置串口 %s 的参数，配置的 %s 不生效，将沿用系统设置",
+			runtime.GOOS, runtime.GOARCH, cfg.Path, s)
+	}
+
+	return &serialTransport{path: cfg.Path, settings: s}, nil
+}
+
+func (t *serialTransport) Open() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	file, err := openSerial(t.path, t.settings)
+	if err != nil {
+		t.state.fail(err)
+		return err
+	}
+	t.file = file
+	t.state.opened()
+	return nil
+}
+
+func (t *serialTransport) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.file == nil {
+		return 0, fmt.Errorf("串口 %s 未打开", t.path)
+	}
+	n, err := t.file.Write(p)
+	if err != nil {
+		t.state.fail(err)
+	}
+	return n, err
+}
+
+func (t *serialTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.file == nil {
+		return nil
+	}
+	err := t.file.Close()
+	t.file = nil
+	t.state.closed()
+	return err
+}
+
+func (t *serialTransport) Status() TransportStatus {
+	return t.state.status("serial", t.path+" "+t.settings.String())
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestNewSerialTransport(t *testing.T) {
+	tr, err := newSerialTransport(PrinterConfig{Path: "/dev/ttyS0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.Status().Address; got != "/dev/ttyS0 9600 8N1 none" {
+		t.Errorf("默认参数为 %q，应为 9600 8N1 无流控", got)
+	}
+	tr, err = newSerialTransport(PrinterConfig{Path: "COM3", BaudRate: 115200, DataBits: 7, Parity: "Even", StopBits: 2, FlowControl: "RTSCTS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.Status().Address; got != "COM3 115200 7E2 rtscts" {
+		t.Errorf("参数为 %q", got)
+	}
+
+	for _, cfg := range []PrinterConfig{
+		{},
+		{Path: "/dev/ttyS0", BaudRate: 12345},
+		{Path: "/dev/ttyS0", BaudRate: -9600},
+		{Path: "/dev/ttyS0", DataBits: 9},
+		{Path: "/dev/ttyS0", Parity: "mark"},
+		{Path: "/dev/ttyS0", StopBits: 3},
+		{Path: "/dev/ttyS0", FlowControl: "dtrdsr"},
+	} {
+		if _, err := newSerialTransport(cfg); err == nil {
+			t.Errorf("%+v 应返回配置错误", cfg)
+		}
+	}
+}
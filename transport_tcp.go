@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// tcpTransport 网络打印机传输通道（RAW 9100 / JetDirect）
+type tcpTransport struct {
+	address        string
+	connectTimeout time.Duration
+	writeTimeout   time.Duration
+	keepAlive      time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+
+	state transportState // 状态使用独立的锁
+}
+
+func newTCPTransport(cfg PrinterConfig) (*tcpTransport, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("网络打印机必须配置 address")
+	}
+
+	// 未指定端口时使用 RAW 打印默认端口 9100
+	address := cfg.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "9100")
+	}
+
+	t := &tcpTransport{
+		address:        address,
+		connectTimeout: time.Duration(cfg.ConnectTimeout) * time.Millisecond,
+		writeTimeout:   time.Duration(cfg.WriteTimeout) * time.Millisecond,
+		keepAlive:      time.Duration(cfg.KeepAlive) * time.Second,
+	}
+	if t.connectTimeout <= 0 {
+		t.connectTimeout = 3 * time.Second
+	}
+	if t.writeTimeout <= 0 {
+		t.writeTimeout = 10 * time.Second
+	}
+	if t.keepAlive <= 0 {
+		t.keepAlive = 30 * time.Second
+	}
+	return t, nil
+}
+
+// 每个打印任务建立新连接，任务结束时由 Close 关闭，keepAlive 只作用于这一个任务的连接
+// 不在任务之间保持连接：打印机常在空闲时关闭连接，复用时无法确认数据已送达；
+// 连接失败或尚未发送数据就断开时由打印队列重试，重试时重新建立连接
+func (t *tcpTransport) Open() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn != nil {
+		t.conn.Close()
+		t.conn = nil
+		t.state.closed()
+	}
+	dialer := net.Dialer{Timeout: t.connectTimeout, KeepAlive: t.keepAlive}
+	conn, err := dialer.Dial("tcp", t.address)
+	if err != nil {
+		t.state.fail(err)
+		return err
+	}
+	t.conn = conn
+	t.state.opened()
+	return nil
+}
+
+// 连接断开时返回错误，不重连重发：已发送的部分数据无法撤回，由打印队列决定是否重试
+func (t *tcpTransport) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn == nil {
+		return 0, fmt.Errorf("打印机 %s 未连接", t.address)
+	}
+	t.conn.SetWriteDeadline(time.Now().Add(t.writeTimeout))
+	n, err := t.conn.Write(p)
+	if err != nil {
+		t.state.fail(err)
+	}
+	return n, err
+}
+
+func (t *tcpTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	t.state.closed()
+	return err
+}
+
+func (t *tcpTransport) Status() TransportStatus {
+	return t.state.status("tcp", t.address)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// 本地监听，依次接受连接并把每个连接收到的数据发送到 received
+func listenTCP(t *testing.T) (net.Listener, chan net.Conn, chan []byte) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	accepted := make(chan net.Conn, 4)
+	received := make(chan []byte, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+			go func() {
+				data, _ := io.ReadAll(conn)
+				received <- data
+			}()
+		}
+	}()
+	return ln, accepted, received
+}
+
+func receive(t *testing.T, received chan []byte) string {
+	t.Helper()
+	select {
+	case data := <-received:
+		return string(data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待接收数据超时")
+		return ""
+	}
+}
+
+func TestTCPTransport(t *testing.T) {
+	ln, _, received := listenTCP(t)
+	tr, err := newTCPTransport(PrinterConfig{Address: ln.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if status := tr.Status(); !status.Connected || status.Type != "tcp" {
+		t.Errorf("打开后应为已连接: %+v", status)
+	}
+	if _, err := tr.Write([]byte("\x1B\x40hello")); err != nil {
+		t.Fatal(err)
+	}
+	tr.Close()
+	if got := receive(t, received); got != "\x1B\x40hello" {
+		t.Errorf("打印机收到 %q", got)
+	}
+	if status := tr.Status(); status.Connected {
+		t.Errorf("关闭后应为未连接: %+v", status)
+	}
+}
+
+// 未指定端口时使用 9100
+func TestTCPTransportDefaultPort(t *testing.T) {
+	tr, err := newTCPTransport(PrinterConfig{Address: "192.168.1.100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.address != "192.168.1.100:9100" {
+		t.Errorf("地址为 %s，应为 192.168.1.100:9100", tr.address)
+	}
+	if _, err := newTCPTransport(PrinterConfig{}); err == nil {
+		t.Error("未配置 address 应返回错误")
+	}
+}
+
+// 每个任务使用新连接：打印机关闭空闲连接后，下一个任务的数据完整送达
+func TestTCPTransportReconnect(t *testing.T) {
+	ln, accepted, received := listenTCP(t)
+	tr, err := newTCPTransport(PrinterConfig{Address: ln.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, job := range []string{"first", "second"} {
+		if err := tr.Open(); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := tr.Write([]byte(job)); err != nil || n != len(job) {
+			t.Fatalf("写入 %q: n=%d, err=%v", job, n, err)
+		}
+		tr.Close()
+		if got := receive(t, received); got != job {
+			t.Errorf("打印机收到 %q，应为 %q", got, job)
+		}
+		// 打印机关闭本次连接（如空闲超时）
+		(<-accepted).Close()
+	}
+
+	// 关闭后不再写入旧连接
+	if _, err := tr.Write([]byte("lost")); err == nil {
+		t.Error("任务结束后写入应返回未连接错误")
+	}
+}
+
+func TestTCPTransportRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	ln.Close()
+
+	tr, err := newTCPTransport(PrinterConfig{Address: address, ConnectTimeout: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tr.Open()
+	if err == nil {
+		t.Fatal("连接被拒绝时应返回错误")
+	}
+	if !retryable(err) {
+		t.Errorf("连接被拒绝应可以重试: %v", err)
+	}
+	if status := tr.Status(); status.Connected || status.LastError == "" {
+		t.Errorf("状态应记录最近一次错误: %+v", status)
+	}
+}
+
+// 写入阻塞（打印机不接收数据）时查询状态不等待写入完成
+func TestTCPTransportStatusDuringWrite(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	tr, err := newTCPTransport(PrinterConfig{Address: ln.Addr().String(), WriteTimeout: 10000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Open(); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := tr.Write(make([]byte, 64<<20))
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	status := make(chan TransportStatus, 1)
+	go func() { status <- tr.Status() }()
+	select {
+	case s := <-status:
+		if !s.Connected {
+			t.Errorf("写入期间应为已连接: %+v", s)
+		}
+	case <-time.After(time.Second):
+		t.Error("写入阻塞时查询状态也被阻塞")
+	}
+
+	conn.Close()
+	if err := <-done; err == nil {
+		t.Error("打印机关闭连接后写入应返回错误")
+	}
+	tr.Close()
+	if s := tr.Status(); s.Connected || s.LastError == "" {
+		t.Errorf("状态应记录写入错误: %+v", s)
+	}
+}
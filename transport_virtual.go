@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/xiaofeiwuuu/tiaoxingma/escpos"
+)
+
+// virtualTransport 虚拟打印机：解释 ESC/POS 指令，每个任务保存为 PNG 图片和原始字节流
+// 用于没有实体打印机时查看实际打印效果
+type virtualTransport struct {
+	dir   string
+	width int
+	dpi   int
+
+	mu  sync.Mutex
+	buf *bytes.Buffer
+	seq int
+
+	state transportState // 状态使用独立的锁
+}
+
+func newVirtualTransport(cfg PrinterConfig) (*virtualTransport, error) {
+	dir := cfg.Path
+	if dir == "" {
+		dir = "receipts"
+	}
+	return &virtualTransport{dir: dir, width: cfg.printWidth(), dpi: cfg.dpi()}, nil
+}
+
+func (t *virtualTransport) Open() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := os.MkdirAll(t.dir, 0755); err != nil {
+		err = fmt.Errorf("创建输出目录 %s 失败: %v", t.dir, err)
+		t.state.fail(err)
+		return err
+	}
+	t.buf = &bytes.Buffer{}
+	t.state.opened()
+	return nil
+}
+
+func (t *virtualTransport) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.buf == nil {
+		return 0, fmt.Errorf("虚拟打印机 %s 未打开", t.dir)
+	}
+	return t.buf.Write(p)
+}
+
+// Close 结束任务：渲染并保存 <时间>-<序号>.png 和 .bin
+func (t *virtualTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.buf == nil {
+		return nil
+	}
+	data := t.buf.Bytes()
+	t.buf = nil
+	t.state.closed()
+	t.seq++
+	base := filepath.Join(t.dir, fmt.Sprintf("%s-%03d", time.Now().Format("20060102-150405"), t.seq))
+
+	if err := os.WriteFile(base+".bin", data, 0644); err != nil {
+		t.state.fail(err)
+		return err
+	}
+	receipt := escpos.Render(data, t.width, t.dpi)
+	file, err := os.Create(base + ".png")
+	if err != nil {
+		t.state.fail(err)
+		return err
+	}
+	err = receipt.WritePNG(file)
+	// 关闭文件时才写入磁盘的错误同样视为打印失败
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.state.fail(err)
+		return err
+	}
+
+	log.Printf("虚拟打印机输出: %s.png", base)
+	for _, w := range receipt.Warnings {
+		log.Printf("  警告: %s", w)
+	}
+	if len(receipt.Warnings) > 0 {
+		t.state.fail(fmt.Errorf("%d条警告，首条: %s", len(receipt.Warnings), receipt.Warnings[0]))
+	}
+	return nil
+}
+
+func (t *virtualTransport) Status() TransportStatus {
+	return t.state.status("virtual", t.dir)
+}
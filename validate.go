@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xiaofeiwuuu/tiaoxingma/barcode"
+)
+
+// 打印前按条码类型校验数据，数据有误时不占用打印机
+// 一维条码由软件编码器逐字符检查，错误中包含无效字符的位置；二维码检查参数和容量
+func validateBarcode(req *PrintRequest) error {
+	barcodeType := strings.ToUpper(req.BarcodeType)
+
+	var err error
+	switch barcodeType {
+	case "QR":
+		_, err = checkQRCode(req)
+	case "PDF417":
+		_, err = checkPDF417(req)
+	case "DATAMATRIX":
+		// 容量在编码时检查
+		if req.BarcodeData == "" {
+			err = fmt.Errorf("Data Matrix数据不能为空")
+		}
+	case "GS1DATAMATRIX":
+		_, err = barcode.ParseGS1Elements(req.BarcodeData)
+	default:
+		_, err = barcode.Encode(barcodeType, req.BarcodeData)
+	}
+	if err == nil {
+		return nil
+	}
+
+	// 统一返回结构化错误
+	var de detailedError
+	if errors.As(err, &de) {
+		return err
+	}
+	return &barcode.DataError{BarcodeType: barcodeType, Reason: err.Error()}
+}